@@ -0,0 +1,142 @@
+#!/bin/bash
+
+# benchmarks every test file with every engine, keeping the best time of several runs to smooth out noise
+#
+# usage: bench.sh [baseline file or git ref]
+#   results are written to bench_output.txt - pass a previous bench_output.txt as the baseline to fail if anything
+#   got a lot slower since then
+#   passing a git ref (e.g. a commit from before an optimization) instead builds that version and times it alongside
+#   the current one, so both the before and after times end up in bench_output.txt - versions from before the -e
+#   flag only have the pipeline engine, so only that engine is compared against them
+#
+# extra solver flags can be passed in BENCH_ARGS to compare heuristics (they aren't passed to a baseline ref), e.g.
+#   BENCH_ARGS="--location-order static" bash bench.sh
+# node counts don't depend on the machine, so they are the fairest way to compare heuristics
+
+SCRIPT_DIR="$(dirname "$(realpath "$0")")"
+TEST_FILE_DIR="test_files"
+ENGINES=("pipeline" "unified" "dlx" "decompose")
+OUTPUT_FILE="$SCRIPT_DIR/bench_output.txt"
+BASELINE_FILE="$1"
+
+RUNS=${BENCH_RUNS:-5}
+read -r -a EXTRA_ARGS <<< "$BENCH_ARGS"
+# how many times slower than the baseline a result can be before it counts as a regression - kept loose since
+# timings this small are noisy
+REGRESSION_FACTOR=${BENCH_REGRESSION_FACTOR:-1.5}
+# results faster than this are too small to compare against the baseline at all
+MIN_COMPARED_SECONDS=${BENCH_MIN_COMPARED_SECONDS:-0.005}
+
+# builds the solver at the given directory into the given binary
+build_solver() {
+    (cd "$1" && go build -o "$2" .)
+}
+
+# prints the best time and the node count of the last run, or nothing if the solver didn't complete
+# usage: time_solver binary file [flags...]
+time_solver() {
+    local binary="$1" file="$2"
+    shift 2
+    local best="" nodes="" run_output seconds
+    for ((run = 0; run < RUNS; run++)); do
+        run_output=$("$binary" --f "$file" "$@")
+        seconds=$(echo "$run_output" | grep -o "Completed in [0-9.]*" | grep -o "[0-9.]*$")
+        nodes=$(echo "$run_output" | grep -oE "(in|visiting) [0-9]+ nodes" | grep -oE "[0-9]+")
+        if [[ -z "$seconds" ]]; then
+            return
+        fi
+        if [[ -z "$best" ]] || awk "BEGIN { exit !($seconds < $best) }"; then
+            best="$seconds"
+        fi
+    done
+    echo "$best ${nodes:--}"
+}
+
+# build once so compile time isn't measured
+BINARY="$(mktemp)"
+BASELINE_BINARY=""
+BASELINE_WORKTREE=""
+cleanup() {
+    rm -f "$BINARY" "$BASELINE_BINARY"
+    if [[ -n "$BASELINE_WORKTREE" ]]; then
+        git -C "$SCRIPT_DIR" worktree remove --force "$BASELINE_WORKTREE"
+    fi
+}
+trap cleanup EXIT
+if ! build_solver "$SCRIPT_DIR" "$BINARY"; then
+    echo "Build failed"
+    exit 1
+fi
+
+# read the baseline before anything is written, in case it is the output file itself
+declare -A baseline
+if [[ -n "$BASELINE_FILE" && -f "$BASELINE_FILE" ]]; then
+    while read -r engine file seconds _; do
+        baseline["$engine $file"]="$seconds"
+    done < "$BASELINE_FILE"
+elif [[ -n "$BASELINE_FILE" ]]; then
+    if ! git -C "$SCRIPT_DIR" rev-parse --verify --quiet "$BASELINE_FILE^{commit}" > /dev/null; then
+        echo "Baseline $BASELINE_FILE is neither a file nor a git ref"
+        exit 1
+    fi
+    BASELINE_WORKTREE="$(mktemp -d)"
+    BASELINE_BINARY="$(mktemp)"
+    if ! git -C "$SCRIPT_DIR" worktree add --quiet --detach "$BASELINE_WORKTREE" "$BASELINE_FILE" ||
+        ! build_solver "$BASELINE_WORKTREE" "$BASELINE_BINARY"; then
+        echo "Build of baseline $BASELINE_FILE failed"
+        exit 1
+    fi
+    # older versions have a single pipeline and no flag to pick an engine
+    baseline_has_engines="false"
+    if "$BASELINE_BINARY" -h 2>&1 | grep -qE "^  -e "; then
+        baseline_has_engines="true"
+    fi
+fi
+
+bench_passed="true"
+: > "$OUTPUT_FILE"
+
+if [[ -n "$BASELINE_BINARY" ]]; then
+    echo -e "Benchmarking known working test files against $BASELINE_FILE (best of $RUNS runs)...\n"
+else
+    echo -e "Benchmarking known working test files (best of $RUNS runs)...\n"
+fi
+printf "%-10s %-28s %12s %12s %10s\n" "ENGINE" "FILE" "SECONDS" "BASELINE" "NODES"
+for engine in "${ENGINES[@]}"; do
+    for file in "$SCRIPT_DIR/$TEST_FILE_DIR/"*.json; do
+        name="$(basename "$file")"
+
+        read -r best nodes <<< "$(time_solver "$BINARY" "$file" --e "$engine" "${EXTRA_ARGS[@]}")"
+        if [[ -z "$best" ]]; then
+            echo "$name did not complete with engine $engine"
+            bench_passed="false"
+            continue
+        fi
+
+        base="${baseline["$engine $name"]}"
+        if [[ -n "$BASELINE_BINARY" && "$baseline_has_engines" == "true" ]]; then
+            read -r base _ <<< "$(time_solver "$BASELINE_BINARY" "$file" --e "$engine")"
+        elif [[ -n "$BASELINE_BINARY" && "$engine" == "pipeline" ]]; then
+            read -r base _ <<< "$(time_solver "$BASELINE_BINARY" "$file")"
+        fi
+        # the baseline time is kept as the last column so the file still works as a baseline itself
+        echo "$engine $name $best $nodes ${base:--}" >> "$OUTPUT_FILE"
+
+        printf "%-10s %-28s %12s %12s %10s" "$engine" "$name" "$best" "${base:--}" "$nodes"
+        if [[ -n "$base" ]] && awk "BEGIN { exit !($best > $MIN_COMPARED_SECONDS && $best > $base * $REGRESSION_FACTOR) }"; then
+            printf "  REGRESSION"
+            bench_passed="false"
+        fi
+        printf "\n"
+    done
+done
+
+echo -e "\nResults written to $OUTPUT_FILE"
+
+# return the overall success/failure status
+if [[ "$bench_passed" == "false" ]]; then
+    echo "Result: FAIL"
+    exit 1 # failure
+fi
+echo "Result: PASS"
+exit 0 # success
@@ -1,5 +1,3 @@
 module djlovell/nyt_pips_solver
 
 go 1.25
-
-require github.com/google/uuid v1.6.0
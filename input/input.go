@@ -14,9 +14,23 @@ type Game struct {
 }
 
 type Condition struct {
-	Expression *string `json:"expression"`
-	Operand    *int    `json:"operand"`
-	Cells      []conditionCell
+	Expression *string         `json:"expression"`
+	Operand    *int            `json:"operand,omitempty"`
+	Cells      []conditionCell `json:"cells"`
+}
+
+// NewCondition - a condition on the cells at the given (x, y) positions - the operand is left out for expressions
+// that don't use one
+func NewCondition(expression string, operand int, cells [][2]int) Condition {
+	c := Condition{Expression: &expression}
+	if expression != "=" && expression != "!=" {
+		c.Operand = &operand
+	}
+	for _, xy := range cells {
+		x, y := xy[0], xy[1]
+		c.Cells = append(c.Cells, conditionCell{X: &x, Y: &y})
+	}
+	return c
 }
 
 type conditionCell struct {
@@ -43,3 +57,44 @@ func ReadFile(filename string) (*Game, error) {
 
 	return g, nil
 }
+
+func WriteFile(filename string, g *Game) error {
+	if g == nil {
+		panic("nil game")
+	}
+	outputJSON, err := json.MarshalIndent(g, "", "    ")
+	if err != nil {
+		return fmt.Errorf("JSON encoding failed with following error - %w", err)
+	}
+	if err := os.WriteFile(filename, append(outputJSON, '\n'), 0o644); err != nil {
+		return errors.New("failed to write JSON file")
+	}
+
+	return nil
+}
+
+// Board - dominoes already placed on a game board, e.g. a puzzle partway through being solved
+type Board struct {
+	Placements *[]Placement `json:"placements"`
+}
+
+// Placement - a domino placed on two neighboring cells, with values in the same order as the cells
+type Placement struct {
+	Cells  []conditionCell `json:"cells"`
+	Values []int           `json:"values"`
+}
+
+func ReadBoardFile(filename string) (*Board, error) {
+	// load the placed dominoes from input
+	inputJSON, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New("failed to read JSON file")
+	}
+
+	b := new(Board)
+	if err := json.Unmarshal(inputJSON, b); err != nil {
+		return nil, fmt.Errorf("JSON parse failed with following error - %w", err)
+	}
+
+	return b, nil
+}
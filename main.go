@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"djlovell/nyt_pips_solver/input"
 	"djlovell/nyt_pips_solver/solver"
 	"errors"
@@ -8,14 +9,39 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"sync"
-	"time"
 )
 
+// exit code used when the search is cut short by --timeout or --max-nodes, so scheduled jobs can tell an
+// incomplete search apart from a finished one
+const exitCodeBudgetExceeded = 2
+
 func main() {
 	// define CL argument for specifying input json file
 	inputFilename := flag.String("f", "", "Input file (JSON)")
 	verbose := flag.Bool("v", false, "Enable debug output (it's not gonna be pretty...)")
+	engine := flag.String("e", "pipeline", "Solving engine - pipeline (arrangements, then values), unified (single pass), dlx (exact cover), or decompose (independent sub-puzzles)")
+	distinct := flag.String("distinct", "layout", "What makes solutions different - grid (distinct pip grids) or layout (distinct domino layouts)")
+	first := flag.Bool("first", false, "Stop as soon as a valid solution is found (same as --max-solutions 1)")
+	maxSolutions := flag.Int("max-solutions", 0, "Stop once this many valid solutions are found (0 finds them all)")
+	timeout := flag.Duration("timeout", 0, "Stop searching after this long, e.g. 30s or 5m (0 for no limit)")
+	maxNodes := flag.Int64("max-nodes", 0, "Stop searching after visiting this many search nodes (0 for no limit)")
+	locationOrder := flag.String("location-order", "fewest-choices", "Pipeline engine location order - fewest-choices (most constrained location next) or static (sorted once by blacklist size)")
+	unique := flag.Bool("unique", false, "Check whether the puzzle has exactly one solution, stopping as soon as a second one is found")
+	explain := flag.Bool("explain", false, "Solve step by step like a person would, explaining each step")
+	hint := flag.Bool("hint", false, "Reveal the single easiest fact that can be deduced next, and why")
+	boardFilename := flag.String("board", "", "Dominoes already placed (JSON), for --hint - leave out for an empty board")
+	rate := flag.Bool("rate", false, "Rate how hard the puzzle is, with a breakdown of what went into the rating")
+	count := flag.Bool("count", false, "Only count the solutions (by layout) instead of finding and printing them")
+	ttMemory := flag.Int64("tt-memory", 64, "Memory cap in MB for remembering search states already searched in full (0 to turn it off)")
+	minimize := flag.String("minimize", "", "Remove conditions and loosen sums while the solution stays unique, writing the leanest puzzle to this file (JSON)")
+	generate := flag.String("generate", "", "Generate a puzzle with a unique solution and write it to this file (JSON) instead of solving one")
+	seed := flag.Uint64("seed", 1, "Random seed for --generate - the same seed and settings always generate the same puzzle")
+	width := flag.Int("width", 7, "Width of the grid --generate grows the board in")
+	height := flag.Int("height", 7, "Height of the grid --generate grows the board in")
+	regions := flag.Int("regions", 0, "Number of regions --generate starts with (0 for about one per three cells)")
+	difficulty := flag.String("difficulty", "medium", "Difficulty --generate aims for - easy, medium, or hard")
+	attempts := flag.Int("attempts", 50, "Boards --generate tries before settling for the one closest to the difficulty")
+	valueOrder := flag.String("value-order", "input", "Pipeline engine domino order - input (as listed in the input file) or tightest-sum (closest to meeting adjacent sums first)")
 
 	flag.Parse()
 	if inputFilename == nil {
@@ -24,6 +50,142 @@ func main() {
 	if verbose == nil {
 		panic("verbose flag should have defaulted to something")
 	}
+	if engine == nil {
+		panic("engine flag should have defaulted to something")
+	}
+	solvingEngine, err := solver.ParseEngine(*engine)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	if distinct == nil {
+		panic("distinct flag should have defaulted to something")
+	}
+	solutionIdentity, err := solver.ParseSolutionIdentity(*distinct)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	if first == nil {
+		panic("first flag should have defaulted to something")
+	}
+	if maxSolutions == nil {
+		panic("max solutions flag should have defaulted to something")
+	}
+	if *maxSolutions < 0 {
+		fmt.Println("Error: max solutions cannot be a negative number")
+		return
+	}
+	if *first {
+		*maxSolutions = 1
+	}
+	if timeout == nil {
+		panic("timeout flag should have defaulted to something")
+	}
+	if maxNodes == nil {
+		panic("max nodes flag should have defaulted to something")
+	}
+	if *timeout < 0 || *maxNodes < 0 {
+		fmt.Println("Error: timeout and max nodes cannot be negative")
+		return
+	}
+	if locationOrder == nil {
+		panic("location order flag should have defaulted to something")
+	}
+	searchLocationOrder, err := solver.ParseLocationOrder(*locationOrder)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	if valueOrder == nil {
+		panic("value order flag should have defaulted to something")
+	}
+	searchValueOrder, err := solver.ParseValueOrder(*valueOrder)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	if count == nil {
+		panic("count flag should have defaulted to something")
+	}
+	if unique == nil {
+		panic("unique flag should have defaulted to something")
+	}
+	if explain == nil {
+		panic("explain flag should have defaulted to something")
+	}
+	if hint == nil {
+		panic("hint flag should have defaulted to something")
+	}
+	if boardFilename == nil {
+		panic("board file name flag should have at least defaulted to empty")
+	}
+	if rate == nil {
+		panic("rate flag should have defaulted to something")
+	}
+	if minimize == nil {
+		panic("minimize file name flag should have at least defaulted to empty")
+	}
+	if modes := btoi(*count) + btoi(*unique) + btoi(*explain) + btoi(*hint) + btoi(*rate) + btoi(*minimize != ""); modes > 1 {
+		fmt.Println("Error: choose only one of counting solutions, checking uniqueness, explaining a solution, a hint, a rating, or minimizing")
+		return
+	}
+	if *boardFilename != "" && !*hint {
+		fmt.Println("Error: a board file can only be given with --hint")
+		return
+	}
+	if *count && solutionIdentity != solver.SolutionIdentityDominoLayout {
+		fmt.Println("Error: solutions can only be counted by layout")
+		return
+	}
+	if ttMemory == nil {
+		panic("transposition memory flag should have defaulted to something")
+	}
+	if *ttMemory < 0 {
+		fmt.Println("Error: transposition table memory cannot be negative")
+		return
+	}
+	searchOpts := solver.SearchOptions{
+		Engine:       solvingEngine,
+		Identity:     solutionIdentity,
+		MaxSolutions: *maxSolutions,
+		Timeout:      *timeout,
+		MaxNodes:     *maxNodes,
+		Order:        solver.SearchOrder{Locations: searchLocationOrder, Values: searchValueOrder},
+		// flag is in MB
+		TranspositionMemory: *ttMemory << 20,
+	}
+
+	if generate == nil || seed == nil || width == nil || height == nil || regions == nil || difficulty == nil || attempts == nil {
+		panic("generate flags should have defaulted to something")
+	}
+	if *generate != "" {
+		if *inputFilename != "" || btoi(*count)+btoi(*unique)+btoi(*explain)+btoi(*hint)+btoi(*rate)+btoi(*minimize != "") > 0 {
+			fmt.Println("Error: --generate writes a new puzzle, so it can't be given an input file or another mode")
+			return
+		}
+		if !strings.HasSuffix(*generate, ".json") {
+			fmt.Println("Error: output file should be of the format *.json")
+			fmt.Println(*generate)
+			return
+		}
+		targetDifficulty, err := solver.ParseDifficultyBucket(*difficulty)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		solver.SetDebugPrint(*verbose)
+		generatePuzzle(*generate, solver.GenerateOptions{
+			Seed:       *seed,
+			Width:      *width,
+			Height:     *height,
+			Regions:    *regions,
+			Difficulty: targetDifficulty,
+			Attempts:   *attempts,
+			Search:     searchOpts,
+		})
+		return
+	}
 
 	if !strings.HasSuffix(*inputFilename, ".json") {
 		fmt.Println("Error: input file should be of the format *.json")
@@ -50,74 +212,63 @@ func main() {
 	}
 	game.Print()
 
-	// start a timer for solving
-	startTime := time.Now()
-
-	// calculate possible ways dominoes can fit on the game board
-	fmt.Println("Calculating possible domino arrangements...")
-	fmt.Println()
-	dominoArrangementChan := make(chan solver.DominoArrangement)
-	{
-		wg := new(sync.WaitGroup)
-		wg.Go(func() {
-			solver.GetDominoArrangements(game, dominoArrangementChan)
+	if *count {
+		countSolutions(game, solver.CountOptions{
+			Timeout:             searchOpts.Timeout,
+			MaxNodes:            searchOpts.MaxNodes,
+			TranspositionMemory: searchOpts.TranspositionMemory,
 		})
-		go func() {
-			wg.Wait()
-			close(dominoArrangementChan)
-		}()
+		return
 	}
 
-	// get possible solutions for each arrangement
-	fmt.Println("Calculating possible solutions...")
-	fmt.Println()
-	possibleSolutionChan := make(chan solver.Solution)
-	{
-		// calculate possible solutions for each arrangement in parallel
-		wg := new(sync.WaitGroup)
-		for a := range dominoArrangementChan {
-			wg.Go(func() {
-				solver.GetPossibleSolutionsForArrangement(game, &a, possibleSolutionChan)
-			})
+	if *unique {
+		checkUniqueness(game, searchOpts)
+		return
+	}
+	if *explain {
+		explainSolution(game, searchOpts)
+		return
+	}
+	if *rate {
+		ratePuzzle(game, searchOpts)
+		return
+	}
+	if *minimize != "" {
+		if !strings.HasSuffix(*minimize, ".json") {
+			fmt.Println("Error: output file should be of the format *.json")
+			fmt.Println(*minimize)
+			return
 		}
-		go func() {
-			wg.Wait()
-			close(possibleSolutionChan)
-		}()
+		minimizePuzzle(inputGame, *minimize, searchOpts)
+		return
 	}
-
-	// find valid solutions
-	fmt.Println("Testing possible solutions...")
-	fmt.Println()
-	validSolutionChan := make(chan solver.Solution)
-	{
-		// use a worker pool to check solutions in parallel
-		numCheckers := 100
-		wg := new(sync.WaitGroup)
-		for range numCheckers {
-			wg.Go(func() {
-				for s := range possibleSolutionChan {
-					if correct := solver.CheckSolution(game, &s); correct {
-						validSolutionChan <- s
-					}
-				}
-			})
+	if *hint {
+		var board *solver.PartialBoard
+		if *boardFilename != "" {
+			inputBoard, err := input.ReadBoardFile(*boardFilename)
+			if err != nil {
+				fmt.Printf("Error: board file read failed with error - %s\n", err.Error())
+				return
+			}
+			if board, err = solver.ParsePartialBoard(game, inputBoard); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				return
+			}
 		}
-		go func() {
-			wg.Wait()
-			close(validSolutionChan)
-		}()
+		giveHint(game, board, solver.CountOptions{
+			Timeout:             searchOpts.Timeout,
+			MaxNodes:            searchOpts.MaxNodes,
+			TranspositionMemory: searchOpts.TranspositionMemory,
+		})
+		return
 	}
 
-	validSolutions := make([]solver.Solution, 0)
-	for s := range validSolutionChan {
-		validSolutions = append(validSolutions, s)
-	}
+	fmt.Printf("Searching for solutions with the %s engine...\n\n", searchOpts.Engine.String())
+	result := solver.Search(context.Background(), game, searchOpts)
 
 	fmt.Println(strings.Repeat("*", 64))
-	defer fmt.Println(strings.Repeat("*", 64))
-	fmt.Printf("NYT Pips Solver Completed in %f seconds. ", time.Since(startTime).Seconds())
-	switch l := len(validSolutions); l {
+	fmt.Printf("NYT Pips Solver Completed in %f seconds. ", result.Elapsed.Seconds())
+	switch l := len(result.Solutions); l {
 	case 0:
 		fmt.Println("No valid solutions found (RIP).")
 	case 1:
@@ -126,7 +277,238 @@ func main() {
 	default:
 		fmt.Printf("Found %d valid solutions.\n\nGo try them on the NYT Games app/site!\n\n", l)
 	}
-	for _, s := range validSolutions {
+	if result.Exhaustive() {
+		fmt.Printf("Searched every possibility in %d nodes.\n\n", result.Nodes)
+	} else {
+		fmt.Printf("Stopped early - the search %s after visiting %d nodes", result.StopReason.String(), result.Nodes)
+		if searchOpts.Engine == solver.EnginePipeline {
+			fmt.Printf(" and %d arrangements", result.Arrangements)
+		}
+		fmt.Println(", so there may be more solutions.")
+		fmt.Println()
+	}
+	if searchOpts.Engine == solver.EnginePipeline {
+		fmt.Printf("Tilings pruned before value search: %d\n", result.TilingsPruned)
+		printTranspositionStats(result.Transposition)
+		fmt.Println()
+	}
+	for _, s := range result.Solutions {
+		fmt.Println(s.String())
+	}
+	fmt.Println(strings.Repeat("*", 64))
+
+	if result.BudgetExceeded() {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+// prints the number of solutions to a game instead of the solutions themselves
+func countSolutions(game *solver.Game, opts solver.CountOptions) {
+	fmt.Println("Counting solutions...")
+	fmt.Println()
+	result := solver.CountSolutions(context.Background(), game, opts)
+
+	fmt.Println(strings.Repeat("*", 64))
+	fmt.Printf("NYT Pips Solver Completed in %f seconds. ", result.Elapsed.Seconds())
+	if result.Exhaustive() {
+		fmt.Printf("Counted %d valid solutions.\n\n", result.Count)
+		fmt.Printf("Searched every possibility in %d nodes.\n", result.Nodes)
+	} else {
+		fmt.Printf("Counted at least %d valid solutions.\n\n", result.Count)
+		fmt.Printf("Stopped early - the count %s after visiting %d nodes, so there may be more solutions.\n", result.StopReason.String(), result.Nodes)
+	}
+	printTranspositionStats(result.Transposition)
+	fmt.Println(strings.Repeat("*", 64))
+
+	if result.BudgetExceeded() {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+// prints whether a game has exactly one solution, along with the solution, or two solutions that show it doesn't
+func checkUniqueness(game *solver.Game, opts solver.SearchOptions) {
+	fmt.Printf("Checking for a unique solution with the %s engine...\n\n", opts.Engine.String())
+	result := solver.CheckUniqueness(context.Background(), game, opts)
+
+	fmt.Println(strings.Repeat("*", 64))
+	fmt.Printf("NYT Pips Solver Completed in %f seconds. ", result.Search.Elapsed.Seconds())
+	switch result.Verdict {
+	case solver.UniquenessVerdictUnique:
+		fmt.Println("The puzzle is unique - it has exactly one solution.")
+	case solver.UniquenessVerdictAmbiguous:
+		fmt.Printf("The puzzle is ambiguous - these two solutions differ in cells %s.\n", strings.Join(result.DifferingCells, ", "))
+	case solver.UniquenessVerdictUnsolvable:
+		fmt.Println("The puzzle is unsolvable (RIP).")
+	case solver.UniquenessVerdictUnknown:
+		fmt.Printf(
+			"Uniqueness unknown - the search %s after visiting %d nodes and finding %d solutions.\n",
+			result.Search.StopReason.String(), result.Search.Nodes, len(result.Solutions),
+		)
+	}
+	fmt.Println()
+	for _, s := range result.Solutions {
 		fmt.Println(s.String())
 	}
+	fmt.Println(strings.Repeat("*", 64))
+
+	if result.Search.BudgetExceeded() {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+func printTranspositionStats(stats solver.TranspositionStats) {
+	fmt.Printf(
+		"Transposition table: %d hits, %d misses, %d states stored, %d turned away when full\n",
+		stats.Hits, stats.Misses, stats.Stored, stats.Rejected,
+	)
+}
+
+// prints the steps to solve a game by deduction, and the solution they lead to
+func explainSolution(game *solver.Game, opts solver.SearchOptions) {
+	fmt.Println("Solving step by step...")
+	fmt.Println()
+	result := solver.Deduce(context.Background(), game, opts)
+
+	fmt.Println(strings.Repeat("*", 64))
+	for i, step := range result.Steps {
+		fmt.Printf("%3d. %s\n", i+1, step.String())
+	}
+	fmt.Println()
+	switch {
+	case result.Contradiction != "":
+		fmt.Printf("No solution - %s.\n", result.Contradiction)
+	case result.UsedSearch && result.Solution == nil:
+		fmt.Printf("Stopped early - the techniques ran out after %d steps, and the search %s before finding a solution.\n", len(result.Steps), result.StopReason.String())
+	case result.UsedSearch:
+		fmt.Printf("Solved in %d steps, but the techniques ran out and the rest needed a search.\n", len(result.Steps))
+	default:
+		fmt.Printf("Solved in %d steps without any searching - the hardest technique was %s.\n", len(result.Steps), result.HardestTechnique())
+	}
+	fmt.Println()
+	if result.Solution != nil {
+		fmt.Println(result.Solution.String())
+	}
+	fmt.Println(strings.Repeat("*", 64))
+
+	if result.BudgetExceeded() {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+// prints one fact about a game that can be deduced from the dominoes placed so far
+func giveHint(game *solver.Game, board *solver.PartialBoard, opts solver.CountOptions) {
+	result := solver.Hint(context.Background(), game, board, opts)
+
+	fmt.Println(strings.Repeat("*", 64))
+	switch {
+	case result.Inconsistent != "":
+		fmt.Printf("Something is wrong - %s.\n", result.Inconsistent)
+	case result.Solved:
+		fmt.Println("Nothing left to hint - the board is already solved!")
+	default:
+		fmt.Printf("Hint: %s (%s)\n", result.Step.Conclusion, result.Step.Technique)
+		fmt.Printf("Why: %s\n", result.Step.Reason)
+	}
+	if result.BudgetExceeded() {
+		fmt.Printf("Stopped early - checking that the board can still be finished %s, so it might not lead to a solution.\n", result.StopReason.String())
+	}
+	fmt.Println(strings.Repeat("*", 64))
+
+	if result.BudgetExceeded() {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+// prints how hard a game is
+func ratePuzzle(game *solver.Game, opts solver.SearchOptions) {
+	fmt.Println("Rating puzzle...")
+	fmt.Println()
+	rating := solver.RatePuzzle(context.Background(), game, opts)
+
+	fmt.Println(strings.Repeat("*", 64))
+	fmt.Print(rating.String())
+	fmt.Println(strings.Repeat("*", 64))
+
+	if rating.StopReason == solver.StopReasonTimeout || rating.StopReason == solver.StopReasonNodeLimit {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+// generates a puzzle, writes it out, and prints it along with its rating
+func generatePuzzle(outputFilename string, opts solver.GenerateOptions) {
+	fmt.Printf("Generating a puzzle from seed %d, aiming for %s...\n\n", opts.Seed, opts.Difficulty.String())
+	puzzle, err := solver.GeneratePuzzle(context.Background(), opts)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		if errors.Is(err, solver.ErrSearchBudgetExceeded) {
+			os.Exit(exitCodeBudgetExceeded)
+		}
+		return
+	}
+	if err := input.WriteFile(outputFilename, puzzle.Game); err != nil {
+		fmt.Printf("Error: output file write failed with error - %s\n", err.Error())
+		return
+	}
+	game, err := solver.ParseInputGame(puzzle.Game)
+	if err != nil {
+		panic("generated puzzle failed to parse - " + err.Error())
+	}
+	game.Print()
+
+	fmt.Println(strings.Repeat("*", 64))
+	fmt.Printf("Wrote a puzzle with a unique solution to %s after trying %d boards.\n", outputFilename, puzzle.Attempts)
+	if puzzle.OutOfBudget > 0 {
+		fmt.Printf("Skipped %d boards that ran out of search budget checking uniqueness or being rated.\n", puzzle.OutOfBudget)
+	}
+	if puzzle.Rating.Bucket != opts.Difficulty {
+		fmt.Printf("No board came out %s, so this is the closest one.\n", opts.Difficulty.String())
+	}
+	fmt.Println()
+	fmt.Print(puzzle.Rating.String())
+	fmt.Println(strings.Repeat("*", 64))
+
+	if puzzle.BudgetExceeded() {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+// minimizes a puzzle, writes it out, and prints what happened to each condition
+func minimizePuzzle(inputGame *input.Game, outputFilename string, opts solver.SearchOptions) {
+	fmt.Printf("Minimizing puzzle with the %s engine...\n\n", opts.Engine.String())
+	result, err := solver.MinimizePuzzle(context.Background(), inputGame, opts)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		if errors.Is(err, solver.ErrSearchBudgetExceeded) {
+			os.Exit(exitCodeBudgetExceeded)
+		}
+		return
+	}
+	if err := input.WriteFile(outputFilename, result.Game); err != nil {
+		fmt.Printf("Error: output file write failed with error - %s\n", err.Error())
+		return
+	}
+
+	fmt.Println(strings.Repeat("*", 64))
+	fmt.Printf(
+		"Wrote the minimized puzzle to %s - removed %d of %d conditions in %d uniqueness checks.\n\n",
+		outputFilename, result.Removed(), len(result.Conditions), result.Checks,
+	)
+	for _, c := range result.Conditions {
+		fmt.Println(c.String())
+	}
+	if result.BudgetExceeded() {
+		fmt.Printf("\nStopped early - some checks %s, so conditions they couldn't clear were kept.\n", result.StopReason.String())
+	}
+	fmt.Println(strings.Repeat("*", 64))
+
+	if result.BudgetExceeded() {
+		os.Exit(exitCodeBudgetExceeded)
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
 }
@@ -0,0 +1,92 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUntileableBoard - the in-play cells can't be covered by the dominoes no matter what values they hold
+var ErrUntileableBoard = errors.New("board can't be tiled with dominoes")
+
+// boardComponent - a group of in-play cells connected through their neighbors. No domino can cover cells from two
+// different components, so each one can be tiled on its own and the tilings combined afterwards.
+type boardComponent struct {
+	cells cellSet
+	size  int
+}
+
+// the first cell of the component in reading order, used to name the component
+func (c boardComponent) firstCell(g *Game) *cell {
+	return g.cells[c.cells.first()]
+}
+
+// splits the in-play cells into connected components, in reading order of their first cells
+func (g *Game) findComponents() []boardComponent {
+	components := make([]boardComponent, 0)
+	var seen cellSet
+	for _, start := range g.cells {
+		if seen.has(start.index) {
+			continue
+		}
+		component := boardComponent{}
+		stack := []*cell{start}
+		seen.add(start.index)
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			component.cells.add(c.index)
+			component.size++
+			for _, neighbor := range []*cell{c.neighborRight, c.neighborBelow, c.neighborLeft, c.neighborAbove} {
+				if neighbor != nil && !seen.has(neighbor.index) {
+					seen.add(neighbor.index)
+					stack = append(stack, neighbor)
+				}
+			}
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// analyzeBoard - makes sure the in-play cells could be covered by the dominoes at all before searching, since an
+// untileable board would otherwise only show up as zero solutions after a full search. Fills in the board's
+// components along the way.
+//
+// Every domino covers two neighboring cells, which are always one dark and one light square in a checkerboard
+// coloring, so every component needs an even number of cells split evenly between the two colors.
+func (g *Game) analyzeBoard() error {
+	g.components = g.findComponents()
+	for _, component := range g.components {
+		first := component.firstCell(g)
+		if component.size == 1 {
+			return fmt.Errorf("%w - cell %s has no neighbors in play, so no domino can cover it", ErrUntileableBoard, first)
+		}
+		if component.size%2 != 0 {
+			return fmt.Errorf("%w - component containing %s has %d cells, which is odd", ErrUntileableBoard, first, component.size)
+		}
+		dark := 0
+		for _, c := range g.cells {
+			if component.cells.has(c.index) && (c.posX+c.posY)%2 == 0 {
+				dark++
+			}
+		}
+		if light := component.size - dark; dark != light {
+			return fmt.Errorf(
+				"%w - component containing %s has %d dark and %d light cells in a checkerboard coloring, but every domino covers one of each",
+				ErrUntileableBoard, first, dark, light,
+			)
+		}
+	}
+	if len(g.cells) != 2*len(g.dominoes) {
+		return fmt.Errorf(
+			"%w - the %d cells in play need %d dominoes, but there are %d",
+			ErrUntileableBoard, len(g.cells), len(g.cells)/2, len(g.dominoes),
+		)
+	}
+
+	debugPrint(fmt.Printf, "Board has %d cells in play split into %d components\n", len(g.cells), len(g.components))
+	for _, component := range g.components {
+		debugPrint(fmt.Printf, "  component containing %s has %d cells\n", component.firstCell(g), component.size)
+	}
+	return nil
+}
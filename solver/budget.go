@@ -0,0 +1,170 @@
+package solver
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrSearchBudgetExceeded - a search needed to give an answer ran out of time or nodes first
+var ErrSearchBudgetExceeded = errors.New("search ran out of time or nodes")
+
+// SearchBudget - caps how many search nodes may be visited, and keeps count of how many were (along with a few other
+// stats). Safe for concurrent use, so one budget can be shared by every goroutine working on the same search. A nil
+// budget is unlimited.
+type SearchBudget struct {
+	maxNodes      int64 // 0 means unlimited
+	nodes         atomic.Int64
+	arrangements  atomic.Int64
+	tilingsPruned atomic.Int64
+	// tiling locations placed because a cell had only one way to pair up, and ones placed by branching
+	forcedLocations, branchedLocations atomic.Int64
+	// domino placements that led nowhere, and the deepest placement that still had to choose between dominoes
+	backtracks, branchingDepth atomic.Int64
+	exceeded                   atomic.Bool
+}
+
+// NewSearchBudget - creates a budget allowing up to maxNodes search nodes (0 for no limit)
+func NewSearchBudget(maxNodes int64) *SearchBudget {
+	return &SearchBudget{maxNodes: maxNodes}
+}
+
+// spends a search node, returning false once the budget has run out
+func (b *SearchBudget) spendNode() bool {
+	if b == nil {
+		return true
+	}
+	if b.maxNodes == 0 {
+		b.nodes.Add(1)
+		return true
+	}
+	// stop counting at the cap, so the count says how far the search really got
+	for {
+		n := b.nodes.Load()
+		if n >= b.maxNodes {
+			b.exceeded.Store(true)
+			return false
+		}
+		if b.nodes.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// records that a complete domino arrangement was found
+func (b *SearchBudget) countArrangement() {
+	if b == nil {
+		return
+	}
+	b.arrangements.Add(1)
+}
+
+// records that a partial tiling was abandoned because it could no longer be filled with the dominoes
+func (b *SearchBudget) countPrunedTiling() {
+	if b == nil {
+		return
+	}
+	b.tilingsPruned.Add(1)
+}
+
+// records that a tiling location was placed because one of its cells had no other neighbor to pair with
+func (b *SearchBudget) countForcedLocation() {
+	if b == nil {
+		return
+	}
+	b.forcedLocations.Add(1)
+}
+
+// records that a tiling location was placed as one of several choices
+func (b *SearchBudget) countBranchedLocation() {
+	if b == nil {
+		return
+	}
+	b.branchedLocations.Add(1)
+}
+
+// records that a domino placement was tried and had to be taken back
+func (b *SearchBudget) countBacktrack() {
+	if b == nil {
+		return
+	}
+	b.backtracks.Add(1)
+}
+
+// records that the search had to choose between several dominoes at this depth
+func (b *SearchBudget) recordBranch(depth int) {
+	if b == nil {
+		return
+	}
+	for {
+		deepest := b.branchingDepth.Load()
+		if int64(depth) <= deepest || b.branchingDepth.CompareAndSwap(deepest, int64(depth)) {
+			return
+		}
+	}
+}
+
+// Nodes - the number of search nodes visited so far
+func (b *SearchBudget) Nodes() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.nodes.Load()
+}
+
+// Arrangements - the number of complete domino arrangements found so far
+func (b *SearchBudget) Arrangements() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.arrangements.Load()
+}
+
+// TilingsPruned - the number of partial tilings abandoned before value search because they could no longer be
+// filled with the dominoes
+func (b *SearchBudget) TilingsPruned() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.tilingsPruned.Load()
+}
+
+// Exceeded - whether the search was cut short by running out of nodes
+func (b *SearchBudget) Exceeded() bool {
+	if b == nil {
+		return false
+	}
+	return b.exceeded.Load()
+}
+
+// ForcedLocations - the number of tiling locations placed because a cell had only one free neighbor to pair with
+func (b *SearchBudget) ForcedLocations() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.forcedLocations.Load()
+}
+
+// BranchedLocations - the number of tiling locations placed by branching on a cell with several free neighbors
+func (b *SearchBudget) BranchedLocations() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.branchedLocations.Load()
+}
+
+// Backtracks - the number of domino placements that were tried and had to be taken back (pipeline value search only)
+func (b *SearchBudget) Backtracks() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.backtracks.Load()
+}
+
+// BranchingDepth - the deepest placement (counting from 1) that still had to choose between several dominoes
+// (pipeline value search only)
+func (b *SearchBudget) BranchingDepth() int {
+	if b == nil {
+		return 0
+	}
+	return int(b.branchingDepth.Load())
+}
@@ -0,0 +1,14 @@
+package solver
+
+import "context"
+
+// sends a value on a channel unless the context is cancelled first - returns false if it was cancelled, so the
+// caller can stop searching instead of blocking on a reader that has gone away
+func sendOrCancel[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
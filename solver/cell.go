@@ -12,19 +12,29 @@ type cell struct {
 	inPlay bool
 	// the cell's position in the grid (calculated upon board initialization)
 	posX, posY int
+	// position of the cell among the in-play cells in reading order, used to index bitsets and flat arrays while
+	// searching (-1 if not in play)
+	index int
 	// neighbor cells - will be nil if neighbor is unused
 	neighborLeft, neighborAbove, neighborRight, neighborBelow *cell
 	// applicable conditions - this might be useful later for preemptively eliminating invalid solutions early in domino placement
 	// e.g. if the first domino is placed such that a "5" is on a cell with condition "sum < 5" or "4", that solution-path can
 	// be early terminated
 	applicableConditions []*condition
+	// values the cell could hold before any dominoes are placed - only values found on the dominoes, narrowed further
+	// by what the conditions allow
+	domain valueDomain
 }
 
-// unique identifier for a cell based on its position
+// unique identifier for a cell based on its position - only used for display and input, searches go by index
 func (c cell) identifier() string {
 	return boardPosToCellIdentifier(c.posX, c.posY)
 }
 
+func (c cell) String() string {
+	return c.identifier()
+}
+
 // recovers a cell identifier from x/y positions on a board
 func boardPosToCellIdentifier(posX, posY int) string {
 	return strconv.Itoa(posX) + ":" + strconv.Itoa(posY)
@@ -54,9 +64,9 @@ func cellIdentifierToBoardPos(s string) (int, int, error) {
 func parseInputCell(s string) (*cell, error) {
 	switch s {
 	case "X":
-		return &cell{inPlay: false}, nil
+		return &cell{inPlay: false, index: -1}, nil
 	case "O":
-		return &cell{inPlay: true}, nil
+		return &cell{inPlay: true, index: -1}, nil
 	default:
 		return nil, fmt.Errorf("%s is an unknown input cell type", s)
 	}
@@ -0,0 +1,109 @@
+package solver
+
+import "math/bits"
+
+// the most in-play cells a board can have - far more than any NYT board, but it keeps cell sets a fixed size so
+// they can be copied around by value
+const maxInPlayCells = 256
+
+const cellSetWords = maxInPlayCells / 64
+
+// cellSet - a set of in-play cells stored as a bitset, indexed by cell index
+type cellSet [cellSetWords]uint64
+
+func (s cellSet) has(i int) bool {
+	return s[i/64]&(1<<(i%64)) != 0
+}
+
+func (s *cellSet) add(i int) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+func (s *cellSet) remove(i int) {
+	s[i/64] &^= 1 << (i % 64)
+}
+
+// cells in s that aren't in o
+func (s cellSet) minus(o cellSet) cellSet {
+	for w := range s {
+		s[w] &^= o[w]
+	}
+	return s
+}
+
+// cells in either s or o
+func (s cellSet) union(o cellSet) cellSet {
+	for w := range s {
+		s[w] |= o[w]
+	}
+	return s
+}
+
+// whether every cell in o is also in s
+func (s cellSet) containsAll(o cellSet) bool {
+	for w := range s {
+		if o[w]&^s[w] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s cellSet) isEmpty() bool {
+	for _, word := range s {
+		if word != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s cellSet) size() int {
+	n := 0
+	for _, word := range s {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// lowest cell index in the set (the first in reading order), or -1 if the set is empty
+func (s cellSet) first() int {
+	for w, word := range s {
+		if word != 0 {
+			return w*64 + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
+
+// boardValues - the values placed on the board so far, as a flat array indexed by cell index along with a mask of
+// which cells have been filled
+type boardValues struct {
+	values []int // only meaningful for filled cells
+	filled cellSet
+}
+
+// creates empty board values for a board with numCells in-play cells
+func newBoardValues(numCells int) *boardValues {
+	return &boardValues{values: make([]int, numCells)}
+}
+
+func (b *boardValues) set(cellIndex, v int) {
+	b.values[cellIndex] = v
+	b.filled.add(cellIndex)
+}
+
+func (b *boardValues) unset(cellIndex int) {
+	b.filled.remove(cellIndex)
+}
+
+// returns the value of a cell, and false if the cell hasn't been filled
+func (b *boardValues) get(cellIndex int) (int, bool) {
+	return b.values[cellIndex], b.filled.has(cellIndex)
+}
+
+func (b *boardValues) clone() *boardValues {
+	values := make([]int, len(b.values))
+	copy(values, b.values)
+	return &boardValues{values: values, filled: b.filled}
+}
@@ -11,7 +11,11 @@ import (
 type condition struct {
 	expression      conditionExp
 	operand         int      // goes with some conditions
-	cellIdentifiers []string // cell identifiers
+	cellIdentifiers []string // cell identifiers, for display
+	// filled in once the board is parsed, for searching
+	index    int     // position in Game.conditions
+	cells    []int   // cell indices, in the same order as cellIdentifiers
+	cellMask cellSet // the region of cells the condition covers
 }
 
 type conditionExp int
@@ -63,54 +67,52 @@ var errConditionNotReadyToCheck = errors.New("condition's cells are not ready to
 
 // check - returns if cell values satisfy the condition or not
 // returns errConditionNotReadyToCheck if not all cells have been filled
-func (c condition) check(cellValues map[string] /* cell identifier */ int /*cell value */) (bool, error) {
-	for _, cell := range c.cellIdentifiers {
-		if _, ok := cellValues[cell]; !ok {
-			return false, errConditionNotReadyToCheck
-		}
+func (c condition) check(cellValues *boardValues) (bool, error) {
+	if !cellValues.filled.containsAll(c.cellMask) {
+		return false, errConditionNotReadyToCheck
 	}
 
 	switch c.expression {
 	case conditionExpSumEquals:
 		sum := 0
-		for _, cell := range c.cellIdentifiers {
-			sum += cellValues[cell]
+		for _, cell := range c.cells {
+			sum += cellValues.values[cell]
 		}
 		if sum != c.operand {
 			return false, nil
 		}
 	case conditionExpSumLessThan:
 		sum := 0
-		for _, cell := range c.cellIdentifiers {
-			sum += cellValues[cell]
+		for _, cell := range c.cells {
+			sum += cellValues.values[cell]
 		}
 		if sum >= c.operand {
 			return false, nil
 		}
 	case conditionExpSumGreaterThan:
 		sum := 0
-		for _, cell := range c.cellIdentifiers {
-			sum += cellValues[cell]
+		for _, cell := range c.cells {
+			sum += cellValues.values[cell]
 		}
 		if sum <= c.operand {
 			return false, nil
 		}
 	case conditionExpEquivalent:
 		// just use first value as the "norm" and fail if anything else doesn't match
-		expectedVal := cellValues[c.cellIdentifiers[0]]
-		for _, cell := range c.cellIdentifiers {
-			if cellValues[cell] != expectedVal {
+		expectedVal := cellValues.values[c.cells[0]]
+		for _, cell := range c.cells {
+			if cellValues.values[cell] != expectedVal {
 				return false, nil
 			}
 		}
 	case conditionExpDistinct:
-		foundVals := make(map[int]bool)
-		for _, cell := range c.cellIdentifiers {
-			cellVal := cellValues[cell]
-			if _, alreadyExists := foundVals[cellVal]; alreadyExists {
+		foundVals := valueDomain(0)
+		for _, cell := range c.cells {
+			cellVal := cellValues.values[cell]
+			if foundVals.has(cellVal) {
 				return false, nil
 			}
-			foundVals[cellVal] = true
+			foundVals |= singleValueDomain(cellVal)
 		}
 	default:
 		panic("unexpected condition expression type")
@@ -119,6 +121,167 @@ func (c condition) check(cellValues map[string] /* cell identifier */ int /*cell
 	return true, nil
 }
 
+// propagate - narrows the domains of the condition's unfilled cells using the values already placed.
+// Returns whether any domain changed, and false for ok if the condition can no longer be satisfied.
+func (c condition) propagate(cellValues *boardValues, domains cellDomains) (changed bool, ok bool) {
+	switch c.expression {
+	case conditionExpSumEquals, conditionExpSumLessThan, conditionExpSumGreaterThan:
+		return c.propagateSumBounds(cellValues, domains)
+	case conditionExpEquivalent:
+		return c.propagateEquivalent(cellValues, domains)
+	case conditionExpDistinct:
+		return c.propagateDistinct(cellValues, domains)
+	default:
+		panic("unexpected condition expression type")
+	}
+}
+
+// narrows unfilled cells of a sum condition so that every value left could still reach the target, given the
+// smallest and largest values the other unfilled cells could take
+func (c condition) propagateSumBounds(cellValues *boardValues, domains cellDomains) (changed bool, ok bool) {
+	for {
+		// gather the fixed part of the sum and the bounds of the rest of it
+		filledSum, minRest, maxRest := 0, 0, 0
+		unfilled := make([]int, 0, len(c.cells))
+		for _, cell := range c.cells {
+			if v, filled := cellValues.get(cell); filled {
+				filledSum += v
+				continue
+			}
+			d := domains[cell]
+			if d == 0 {
+				return changed, false
+			}
+			minRest += d.min()
+			maxRest += d.max()
+			unfilled = append(unfilled, cell)
+		}
+
+		// can the target still be reached at all?
+		switch c.expression {
+		case conditionExpSumEquals:
+			if filledSum+minRest > c.operand || filledSum+maxRest < c.operand {
+				return changed, false
+			}
+		case conditionExpSumLessThan:
+			if filledSum+minRest >= c.operand {
+				return changed, false
+			}
+		case conditionExpSumGreaterThan:
+			if filledSum+maxRest <= c.operand {
+				return changed, false
+			}
+		}
+
+		// tighten each unfilled cell against the bounds of the others
+		passChanged := false
+		for _, cell := range unfilled {
+			d := domains[cell]
+			minOthers := filledSum + minRest - d.min()
+			maxOthers := filledSum + maxRest - d.max()
+			lo, hi := minPipValue, maxPipValue
+			switch c.expression {
+			case conditionExpSumEquals:
+				lo, hi = c.operand-maxOthers, c.operand-minOthers
+			case conditionExpSumLessThan:
+				hi = c.operand - 1 - minOthers
+			case conditionExpSumGreaterThan:
+				lo = c.operand + 1 - maxOthers
+			}
+			cellChanged, cellOK := domains.narrow(cell, d.clamp(lo, hi))
+			if !cellOK {
+				return true, false
+			}
+			if cellChanged {
+				// the bounds used for the other cells are stale now, so take another pass
+				passChanged = true
+				break
+			}
+		}
+		if !passChanged {
+			return changed, true
+		}
+		changed = true
+	}
+}
+
+// collapses every cell of an equivalence condition to a single shared domain - once any cell is filled, that is
+// just the filled value
+func (c condition) propagateEquivalent(cellValues *boardValues, domains cellDomains) (changed bool, ok bool) {
+	shared := fullValueDomain
+	for _, cell := range c.cells {
+		if v, filled := cellValues.get(cell); filled {
+			shared &= singleValueDomain(v)
+		} else {
+			shared &= domains[cell]
+		}
+	}
+	if shared == 0 {
+		return false, false
+	}
+	for _, cell := range c.cells {
+		if cellValues.filled.has(cell) {
+			continue
+		}
+		if cellChanged, _ := domains.narrow(cell, shared); cellChanged {
+			changed = true
+		}
+	}
+	return changed, true
+}
+
+// removes values already used in a distinct condition from the rest of its cells, and makes sure there are still
+// enough different values left to go around (pigeonhole)
+func (c condition) propagateDistinct(cellValues *boardValues, domains cellDomains) (changed bool, ok bool) {
+	for {
+		// values that are taken - either filled, or the only value an unfilled cell has left
+		used := valueDomain(0)
+		unfilled := make([]int, 0, len(c.cells))
+		for _, cell := range c.cells {
+			v, filled := cellValues.get(cell)
+			if !filled {
+				unfilled = append(unfilled, cell)
+				d := domains[cell]
+				if d.size() != 1 {
+					continue
+				}
+				v = d.min()
+			}
+			if used.has(v) {
+				return changed, false
+			}
+			used |= singleValueDomain(v)
+		}
+
+		// remove taken values from every other unfilled cell
+		passChanged := false
+		remaining := valueDomain(0)
+		for _, cell := range unfilled {
+			d := domains[cell]
+			if d.size() == 1 {
+				remaining |= d
+				continue
+			}
+			cellChanged, cellOK := domains.narrow(cell, ^used)
+			if !cellOK {
+				return true, false
+			}
+			passChanged = passChanged || cellChanged
+			remaining |= domains[cell]
+		}
+
+		// pigeonhole - more unfilled cells than values left to give them
+		if remaining.size() < len(unfilled) {
+			return changed || passChanged, false
+		}
+
+		if !passChanged {
+			return changed, true
+		}
+		changed = true
+	}
+}
+
 // parses a Condition from input specification
 func parseInputCondition(input *input.Condition) (*condition, error) {
 	if input == nil {
@@ -0,0 +1,118 @@
+package solver
+
+import "slices"
+
+// conditionTracker - running totals for every condition as cells are filled during a search, so placing a domino only
+// re-evaluates the conditions on the two cells it covers instead of re-adding every condition from scratch. Fills are
+// undone with unfill on backtrack.
+type conditionTracker struct {
+	conditions []*condition             // Game.conditions
+	sums       []int                    // sum of the filled values of each condition, by condition index
+	filled     []int                    // number of each condition's cells that are filled
+	counts     [][maxPipValue + 1]uint8 // how many of each condition's filled cells hold each value
+}
+
+// creates a tracker for a game with nothing filled yet
+func newConditionTracker(g *Game) *conditionTracker {
+	return &conditionTracker{
+		conditions: g.conditions,
+		sums:       make([]int, len(g.conditions)),
+		filled:     make([]int, len(g.conditions)),
+		counts:     make([][maxPipValue + 1]uint8, len(g.conditions)),
+	}
+}
+
+// records a value being placed in a cell
+func (t *conditionTracker) fill(c *cell, v int) {
+	for _, cond := range c.applicableConditions {
+		t.sums[cond.index] += v
+		t.filled[cond.index]++
+		t.counts[cond.index][v]++
+	}
+}
+
+// exactly reverses fill
+func (t *conditionTracker) unfill(c *cell, v int) {
+	for _, cond := range c.applicableConditions {
+		t.sums[cond.index] -= v
+		t.filled[cond.index]--
+		t.counts[cond.index][v]--
+	}
+}
+
+// holds - returns false if any condition on the cell is already broken by the values filled so far. Complete
+// conditions have to be met, and incomplete ones fail as soon as no values for the rest of their cells could fix them.
+func (t *conditionTracker) holds(c *cell) bool {
+	for _, cond := range c.applicableConditions {
+		if !t.conditionHolds(cond) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *conditionTracker) conditionHolds(cond *condition) bool {
+	sum, complete := t.sums[cond.index], t.filled[cond.index] == len(cond.cells)
+	switch cond.expression {
+	case conditionExpSumEquals:
+		// values are never negative, so the sum can only grow
+		return sum == cond.operand || (!complete && sum < cond.operand)
+	case conditionExpSumLessThan:
+		return sum < cond.operand
+	case conditionExpSumGreaterThan:
+		return sum > cond.operand || !complete
+	case conditionExpEquivalent:
+		// at most one value used so far
+		values := 0
+		for _, count := range t.counts[cond.index] {
+			if count > 0 {
+				values++
+			}
+		}
+		return values <= 1
+	case conditionExpDistinct:
+		// no value used twice so far
+		for _, count := range t.counts[cond.index] {
+			if count > 1 {
+				return false
+			}
+		}
+		return true
+	default:
+		panic("unexpected condition expression type")
+	}
+}
+
+// residual - what is left of a condition that matters to filling the rest of its cells: the sum so far for sum
+// conditions, and a bitmask of the values used so far for the others. A complete condition has already been
+// checked, so nothing is left of it (0).
+func (t *conditionTracker) residual(condIdx int) int {
+	cond := t.conditions[condIdx]
+	if t.filled[condIdx] == len(cond.cells) {
+		return 0
+	}
+	switch cond.expression {
+	case conditionExpSumEquals, conditionExpSumLessThan, conditionExpSumGreaterThan:
+		return t.sums[condIdx]
+	case conditionExpEquivalent, conditionExpDistinct:
+		used := 0
+		for v, count := range t.counts[condIdx] {
+			if count > 0 {
+				used |= 1 << v
+			}
+		}
+		return used
+	default:
+		panic("unexpected condition expression type")
+	}
+}
+
+// copy for handing off to another worker
+func (t *conditionTracker) clone() *conditionTracker {
+	return &conditionTracker{
+		conditions: t.conditions,
+		sums:       slices.Clone(t.sums),
+		filled:     slices.Clone(t.filled),
+		counts:     slices.Clone(t.counts),
+	}
+}
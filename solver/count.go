@@ -0,0 +1,158 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CountOptions - settings for CountSolutions
+type CountOptions struct {
+	Timeout  time.Duration // stop after this long (0 for no limit)
+	MaxNodes int64         // stop after visiting this many search nodes (0 for no limit)
+	// memory cap in bytes for remembering the counts of states already searched (0 to not remember any)
+	TranspositionMemory int64
+}
+
+// CountResult - the outcome of counting solutions
+type CountResult struct {
+	// distinct solutions by domino layout - only every solution there is if the count was exhaustive
+	Count         int64
+	StopReason    StopReason
+	Nodes         int64 // search nodes visited
+	Transposition TranspositionStats
+	Elapsed       time.Duration
+}
+
+// Exhaustive - whether every possibility was counted, so Count is the number of solutions there are
+func (r CountResult) Exhaustive() bool {
+	return r.StopReason == StopReasonExhausted
+}
+
+// BudgetExceeded - whether counting ran out of time or nodes before it could finish
+func (r CountResult) BudgetExceeded() bool {
+	return r.StopReason == StopReasonTimeout || r.StopReason == StopReasonNodeLimit
+}
+
+// CountSolutions - counts the valid solutions of a game without building any of them. Solutions are told apart by
+// domino layout, the same as Search with SolutionIdentityDominoLayout, so the count matches the number of solutions
+// Search finds with that identity.
+//
+// Cells are filled in reading order like the unified engine, so two different ways of reaching the same filled
+// cells, with the same dominoes left and the same state of every unfinished condition, have exactly the same
+// completions. Those completions are only counted once and remembered in a transposition table for next time.
+func CountSolutions(ctx context.Context, game *Game, opts CountOptions) CountResult {
+	if game == nil {
+		panic("nil game")
+	}
+	startTime := time.Now()
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Counting solutions...")
+
+	if opts.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, opts.Timeout)
+		defer cancelTimeout()
+	}
+	budget := NewSearchBudget(opts.MaxNodes)
+	table := newTranspositionTable(opts.TranspositionMemory)
+
+	result := CountResult{}
+	result.Count = countFrom(ctx, game, budget, table, newBoardValues(len(game.cells)), newConditionTracker(game), game.newDominoInventory(), 0)
+
+	switch {
+	case budget.Exceeded():
+		result.StopReason = StopReasonNodeLimit
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.StopReason = StopReasonTimeout
+	case ctx.Err() != nil:
+		result.StopReason = StopReasonCancelled
+	default:
+		result.StopReason = StopReasonExhausted
+	}
+	result.Nodes = budget.Nodes()
+	result.Transposition = table.Stats()
+	result.Elapsed = time.Since(startTime)
+	debugPrint(fmt.Printf, "Remembered the counts of %d search states, used %d times\n", result.Transposition.Stored, result.Transposition.Hits)
+	return result
+}
+
+// recursively counts the ways of filling the rest of the board, the same way the unified engine fills it - stops as
+// soon as the count reaches the limit (0 for no limit), for callers that only need to know there are that many
+func countFrom(
+	ctx context.Context,
+	game *Game,
+	budget *SearchBudget,
+	table *transpositionTable,
+	cellValues *boardValues,
+	conditions *conditionTracker,
+	unplacedDominoes dominoInventory,
+	limit int64,
+) int64 {
+	if ctx.Err() != nil || !budget.spendNode() {
+		return 0
+	}
+
+	// base case - every cell is filled, and every condition was checked as its last cell was filled
+	nextCellIndex := game.inPlayCells.minus(cellValues.filled).first()
+	if nextCellIndex == -1 {
+		return 1
+	}
+
+	hash := game.zobrist.cellsHash(cellValues.filled, unplacedDominoes, conditions)
+	if count, ok := table.lookup(hash); ok {
+		return count
+	}
+
+	count := int64(0)
+	nextCell := game.cells[nextCellIndex]
+	for _, neighbor := range []*cell{nextCell.neighborRight, nextCell.neighborBelow} {
+		if neighbor == nil || cellValues.filled.has(neighbor.index) {
+			continue
+		}
+		for kindIdx, nextDomino := range game.dominoKinds {
+			if unplacedDominoes[kindIdx] == 0 {
+				continue
+			}
+			for _, o := range nextDomino.orientations() {
+				if !nextCell.domain.has(o[0]) || !neighbor.domain.has(o[1]) {
+					continue
+				}
+				cellValues.set(nextCell.index, o[0])
+				cellValues.set(neighbor.index, o[1])
+				conditions.fill(nextCell, o[0])
+				conditions.fill(neighbor, o[1])
+
+				if conditions.holds(nextCell) && conditions.holds(neighbor) {
+					unplacedDominoes[kindIdx]--
+					remaining := int64(0)
+					if limit > 0 {
+						remaining = limit - count
+					}
+					count += countFrom(ctx, game, budget, table, cellValues, conditions, unplacedDominoes, remaining)
+					unplacedDominoes[kindIdx]++
+				}
+
+				// backtrack
+				cellValues.unset(neighbor.index)
+				cellValues.unset(nextCell.index)
+				conditions.unfill(neighbor, o[1])
+				conditions.unfill(nextCell, o[0])
+
+				if limit > 0 && count >= limit {
+					return count
+				}
+			}
+		}
+	}
+
+	// a count cut short by the budget or context is only part of the real one, so it can't be remembered - nor can
+	// one stopped at the limit, which returned above
+	if ctx.Err() == nil && !budget.Exceeded() {
+		table.store(hash, count)
+	}
+	return count
+}
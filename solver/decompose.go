@@ -0,0 +1,305 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Decomposition of the board into sub-puzzles that can be solved independently:
+//   - a sub-puzzle is a connected component of in-play cells, merged with any other components it shares a condition
+//     with - no domino or condition crosses from one sub-puzzle to another
+//   - every way of filling each sub-puzzle (a pattern) is found on its own, drawing from the whole domino inventory
+//   - patterns using the same dominoes are grouped, then one group is picked per sub-puzzle so that together they
+//     use every domino exactly once - the only thing tying the sub-puzzles together
+//   - every combination of patterns from the picked groups is a solution
+//
+// The largest sub-puzzle isn't stored - it is filled last, and each of its patterns is matched up with the other
+// sub-puzzles' groups as soon as it is found, so solutions come out while it is being filled and a search that only
+// needs a few can stop early. A board that is a single sub-puzzle is searched just like the unified engine would,
+// with solutions coming out as they are found.
+
+// subPuzzle - in-play cells that have to be solved together
+type subPuzzle struct {
+	cells cellSet
+	size  int
+}
+
+// subPuzzlePattern - one way of filling a sub-puzzle
+type subPuzzlePattern struct {
+	placements []DominoPlacement
+}
+
+// patternGroup - the patterns of a sub-puzzle that use exactly the same dominoes, so any of them can stand in for
+// the others when allocating dominoes
+type patternGroup struct {
+	uses     dominoInventory
+	patterns []subPuzzlePattern
+}
+
+// GetSolutionsDecomposed - finds valid solutions by splitting the board into independent sub-puzzles, filling each
+// one on its own, and then working out which fillings can share the dominoes. Stops early if the context is
+// cancelled or the budget runs out.
+func GetSolutionsDecomposed(ctx context.Context, game *Game, budget *SearchBudget, outSolutions chan<- Solution) {
+	if game == nil {
+		panic("nil game")
+	}
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Calculating solutions by decomposing the board...")
+
+	subPuzzles := game.findSubPuzzles()
+	debugPrint(fmt.Printf, "Board splits into %d sub-puzzles\n", len(subPuzzles))
+
+	// a board with no cells in play is solved by placing nothing, like the other engines find
+	if len(subPuzzles) == 0 {
+		if sendOrCancel(ctx, outSolutions, Solution{dominoPlacements: []DominoPlacement{}}) {
+			debugPrint(fmt.Println, "No cells in play - empty solution added...")
+		}
+		return
+	}
+
+	// the largest sub-puzzle is likely to have the most patterns, so it is the one left to fill last
+	streamed := 0
+	for i, sp := range subPuzzles {
+		if sp.size > subPuzzles[streamed].size {
+			streamed = i
+		}
+	}
+
+	// find the patterns of every other sub-puzzle, grouped by the dominoes they use
+	groups := make([][]patternGroup, 0, len(subPuzzles)-1)
+	for i, sp := range subPuzzles {
+		if i == streamed {
+			continue
+		}
+		spGroups := game.findPatternGroups(ctx, budget, sp)
+		if ctx.Err() != nil || budget.Exceeded() {
+			return
+		}
+		debugPrint(fmt.Printf, "Sub-puzzle containing %s (%d cells) has patterns using %d different sets of dominoes\n", game.cells[sp.cells.first()], sp.size, len(spGroups))
+		if len(spGroups) == 0 {
+			debugPrint(fmt.Println, "Sub-puzzle can't be filled - no solutions...")
+			return
+		}
+		groups = append(groups, spGroups)
+	}
+
+	// allocate dominoes to the sub-puzzles with the fewest options first, to keep the allocation search narrow
+	slices.SortStableFunc(groups, func(l, r []patternGroup) int {
+		return len(l) - len(r)
+	})
+
+	// fill the last sub-puzzle - the others have to use exactly the dominoes each of its patterns leaves unused
+	sp := subPuzzles[streamed]
+	debugPrint(fmt.Printf, "Filling sub-puzzle containing %s (%d cells) last...\n", game.cells[sp.cells.first()], sp.size)
+	chosen := make([]*patternGroup, 0, len(groups))
+	game.fillSubPuzzle(ctx, budget, sp, newBoardValues(len(game.cells)), newConditionTracker(game), game.newDominoInventory(), make([]DominoPlacement, 0, len(game.dominoes)), func(unused dominoInventory, placements []DominoPlacement) {
+		allocateDominoes(ctx, budget, groups, slices.Clone(unused), chosen, func(chosen []*patternGroup) {
+			combinePatterns(ctx, chosen, slices.Clone(placements), func(s Solution) {
+				if sendOrCancel(ctx, outSolutions, s) {
+					debugPrint(fmt.Println, "Sub-puzzle patterns combined and solution added...")
+				}
+			})
+		})
+	})
+}
+
+// splits the board into sub-puzzles - connected components, merged whenever a condition covers cells in more than
+// one of them
+func (g *Game) findSubPuzzles() []subPuzzle {
+	componentOf := make([]int, len(g.cells))
+	for i, component := range g.components {
+		for _, c := range g.cells {
+			if component.cells.has(c.index) {
+				componentOf[c.index] = i
+			}
+		}
+	}
+
+	// union find over the components
+	parent := make([]int, len(g.components))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for _, cond := range g.conditions {
+		root := find(componentOf[cond.cells[0]])
+		for _, cellIndex := range cond.cells[1:] {
+			parent[find(componentOf[cellIndex])] = root
+		}
+	}
+
+	// gather the components by their root, keeping reading order
+	subPuzzles := make([]subPuzzle, 0)
+	subPuzzleOf := make(map[int]int)
+	for i, component := range g.components {
+		root := find(i)
+		idx, ok := subPuzzleOf[root]
+		if !ok {
+			idx = len(subPuzzles)
+			subPuzzleOf[root] = idx
+			subPuzzles = append(subPuzzles, subPuzzle{})
+		}
+		subPuzzles[idx].cells = subPuzzles[idx].cells.union(component.cells)
+		subPuzzles[idx].size += component.size
+	}
+	return subPuzzles
+}
+
+// finds every pattern of a sub-puzzle, grouped by the dominoes they use
+func (g *Game) findPatternGroups(ctx context.Context, budget *SearchBudget, sp subPuzzle) []patternGroup {
+	inventory := g.newDominoInventory()
+	groups := make([]patternGroup, 0)
+	groupIndex := make(map[string]int)
+	g.fillSubPuzzle(ctx, budget, sp, newBoardValues(len(g.cells)), newConditionTracker(g), slices.Clone(inventory), make([]DominoPlacement, 0, sp.size/2), func(unused dominoInventory, placements []DominoPlacement) {
+		uses := make(dominoInventory, len(inventory))
+		for kindIdx := range inventory {
+			uses[kindIdx] = inventory[kindIdx] - unused[kindIdx]
+		}
+		key := uses.key()
+		idx, ok := groupIndex[key]
+		if !ok {
+			idx = len(groups)
+			groupIndex[key] = idx
+			groups = append(groups, patternGroup{uses: uses})
+		}
+		groups[idx].patterns = append(groups[idx].patterns, subPuzzlePattern{placements: slices.Clone(placements)})
+	})
+	return groups
+}
+
+// recursively fills a sub-puzzle the same way the unified engine fills the whole board - the first unfilled cell in
+// reading order is paired with its free neighbor to the right or below, and each domino left is tried there in both
+// orientations
+func (g *Game) fillSubPuzzle(
+	ctx context.Context,
+	budget *SearchBudget,
+	sp subPuzzle,
+	cellValues *boardValues,
+	conditions *conditionTracker,
+	unplacedDominoes dominoInventory,
+	placementsSoFar []DominoPlacement,
+	emit func(dominoInventory, []DominoPlacement),
+) {
+	if ctx.Err() != nil || !budget.spendNode() {
+		return
+	}
+
+	// base case - the sub-puzzle is filled, and its conditions were all checked along the way
+	nextCellIndex := sp.cells.minus(cellValues.filled).first()
+	if nextCellIndex == -1 {
+		emit(unplacedDominoes, placementsSoFar)
+		return
+	}
+
+	nextCell := g.cells[nextCellIndex]
+	for _, neighbor := range []*cell{nextCell.neighborRight, nextCell.neighborBelow} {
+		if neighbor == nil || cellValues.filled.has(neighbor.index) {
+			continue
+		}
+		for kindIdx, nextDomino := range g.dominoKinds {
+			if unplacedDominoes[kindIdx] == 0 {
+				continue
+			}
+			for _, o := range nextDomino.orientations() {
+				// values the conditions ruled out before anything was placed can be skipped without filling them in
+				if !nextCell.domain.has(o[0]) || !neighbor.domain.has(o[1]) {
+					continue
+				}
+				cellValues.set(nextCell.index, o[0])
+				cellValues.set(neighbor.index, o[1])
+				conditions.fill(nextCell, o[0])
+				conditions.fill(neighbor, o[1])
+
+				if conditions.holds(nextCell) && conditions.holds(neighbor) {
+					unplacedDominoes[kindIdx]--
+					placementsSoFar = append(placementsSoFar, DominoPlacement{
+						cell1:       nextCell,
+						cell1Value:  o[0],
+						cell2:       neighbor,
+						cell2Value:  o[1],
+						printString: nextDomino.String(),
+					})
+
+					g.fillSubPuzzle(ctx, budget, sp, cellValues, conditions, unplacedDominoes, placementsSoFar, emit)
+
+					// backtrack
+					placementsSoFar = placementsSoFar[0 : len(placementsSoFar)-1]
+					unplacedDominoes[kindIdx]++
+				}
+
+				cellValues.unset(neighbor.index)
+				cellValues.unset(nextCell.index)
+				conditions.unfill(neighbor, o[1])
+				conditions.unfill(nextCell, o[0])
+			}
+		}
+	}
+}
+
+// recursively picks a pattern group for each sub-puzzle so that every domino is used exactly once, handing each
+// complete allocation to emit
+func allocateDominoes(
+	ctx context.Context,
+	budget *SearchBudget,
+	groups [][]patternGroup, // of each sub-puzzle left to allocate to
+	unallocated dominoInventory,
+	chosen []*patternGroup,
+	emit func([]*patternGroup),
+) {
+	if ctx.Err() != nil || !budget.spendNode() {
+		return
+	}
+
+	// base case - every sub-puzzle has its dominoes, and since the board needs every domino, none are left over
+	if len(groups) == 0 {
+		emit(chosen)
+		return
+	}
+
+	for i := range groups[0] {
+		group := &groups[0][i]
+		fits := true
+		for kindIdx, count := range group.uses {
+			if count > unallocated[kindIdx] {
+				fits = false
+				break
+			}
+		}
+		if !fits {
+			continue
+		}
+
+		for kindIdx, count := range group.uses {
+			unallocated[kindIdx] -= count
+		}
+		allocateDominoes(ctx, budget, groups[1:], unallocated, append(chosen, group), emit)
+
+		// backtrack
+		for kindIdx, count := range group.uses {
+			unallocated[kindIdx] += count
+		}
+	}
+}
+
+// recursively combines one pattern from each chosen group into a solution, for every combination
+func combinePatterns(ctx context.Context, chosen []*patternGroup, placementsSoFar []DominoPlacement, emit func(Solution)) {
+	if ctx.Err() != nil {
+		return
+	}
+	if len(chosen) == 0 {
+		emit(Solution{dominoPlacements: slices.Clone(placementsSoFar)})
+		return
+	}
+	for _, p := range chosen[0].patterns {
+		combinePatterns(ctx, chosen[1:], append(placementsSoFar, p.placements...), emit)
+	}
+}
@@ -0,0 +1,524 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A deduction engine that solves puzzles the way a person would, one named technique at a time, so the solution
+// comes with the reasoning behind it. Techniques are tried from easiest to hardest, and after every step the easiest
+// ones get another go. Only once nothing applies does it fall back to searching.
+//
+// The deductions start from nothing but the values on the dominoes, rather than the domains worked out when the
+// game was parsed, so that every value ruled out is explained by a step.
+
+// Technique - a named way of deducing something about a puzzle, in order from easiest to hardest
+type Technique int
+
+const (
+	// TechniqueDeadEndPairing - a cell with only one neighbor it could share a domino with has to share one with it
+	TechniqueDeadEndPairing Technique = iota
+	// TechniqueDominoValues - a cell can only hold values found on the dominoes left that could cover it
+	TechniqueDominoValues
+	// TechniqueSumBounds - the cells of a sum region can only hold values that let the sum be reached, given the
+	// smallest and largest values the rest of the region could hold
+	TechniqueSumBounds
+	// TechniqueEqualRegion - every cell of an "=" region can only hold values all of them could hold
+	TechniqueEqualRegion
+	// TechniqueOnlyFittingDomino - a pair of cells known to share a domino where only one domino fits
+	TechniqueOnlyFittingDomino
+	// TechniqueDistinctPigeonhole - values taken in a "!=" region are ruled out for the rest of it, and there have to be
+	// enough different values left to go around
+	TechniqueDistinctPigeonhole
+	// TechniqueLastDominoOfAKind - the last domino of a kind has only one place left where it fits
+	TechniqueLastDominoOfAKind
+	// TechniqueSearch - nothing else applies, so the rest is found by trial and error
+	TechniqueSearch
+)
+
+var techniques = []Technique{
+	TechniqueDeadEndPairing, TechniqueDominoValues, TechniqueSumBounds, TechniqueEqualRegion,
+	TechniqueOnlyFittingDomino, TechniqueDistinctPigeonhole, TechniqueLastDominoOfAKind, TechniqueSearch,
+}
+
+func (t Technique) String() string {
+	switch t {
+	case TechniqueDeadEndPairing:
+		return "dead-end pairing"
+	case TechniqueDominoValues:
+		return "domino values"
+	case TechniqueSumBounds:
+		return "sum bounds"
+	case TechniqueEqualRegion:
+		return "equal region"
+	case TechniqueOnlyFittingDomino:
+		return "only fitting domino"
+	case TechniqueDistinctPigeonhole:
+		return "distinct pigeonhole"
+	case TechniqueLastDominoOfAKind:
+		return "last domino of a kind"
+	case TechniqueSearch:
+		return "search"
+	default:
+		panic("unhandled technique")
+	}
+}
+
+// DeductionStep - one fact deduced about the puzzle, and why
+type DeductionStep struct {
+	Technique  Technique
+	Cells      []string // identifiers of the cells the step is about, in reading order
+	Conclusion string   // what was deduced, e.g. "cell 3:4 must be 6"
+	Reason     string   // a sentence of justification
+}
+
+func (s DeductionStep) String() string {
+	return fmt.Sprintf("[%s] %s - %s", s.Technique.String(), s.Conclusion, s.Reason)
+}
+
+// DeductionResult - the steps taken to solve a puzzle by deduction
+type DeductionResult struct {
+	Steps []DeductionStep
+	// the solution reached, nil if there isn't one
+	Solution *Solution
+	// whether the solution needed a search to finish - if so, the last step is a TechniqueSearch step
+	UsedSearch bool
+	// why the puzzle can't be solved, if a step ran into a contradiction or a full search found nothing (empty
+	// otherwise)
+	Contradiction string
+	// why the search ended, if one was needed - a search cut short before finding a solution proves nothing either way
+	StopReason StopReason
+}
+
+// BudgetExceeded - whether the search ran out of time or nodes before it could finish the solution
+func (r DeductionResult) BudgetExceeded() bool {
+	return r.StopReason == StopReasonTimeout || r.StopReason == StopReasonNodeLimit
+}
+
+// HardestTechnique - the hardest technique any step needed
+func (r DeductionResult) HardestTechnique() Technique {
+	hardest := TechniqueDeadEndPairing
+	for _, s := range r.Steps {
+		hardest = max(hardest, s.Technique)
+	}
+	return hardest
+}
+
+// Deduce - solves a game step by step with human techniques, falling back to a search (with the options given) only
+// once no technique applies
+func Deduce(ctx context.Context, game *Game, opts SearchOptions) DeductionResult {
+	if game == nil {
+		panic("nil game")
+	}
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Solving by deduction...")
+
+	state := newDeductionState(game)
+	result := DeductionResult{}
+	for !state.solved() {
+		step, contradiction := state.nextStep()
+		if contradiction != "" {
+			result.Contradiction = contradiction
+			return result
+		}
+		if step == nil {
+			break
+		}
+		debugPrint(fmt.Println, step.String())
+		result.Steps = append(result.Steps, *step)
+	}
+
+	if state.solved() {
+		// dominoes are only placed when nothing else fits, so if the last of them break a condition, nothing works
+		solution := Solution{dominoPlacements: slices.Clone(state.placements)}
+		if !CheckSolution(game, &solution) {
+			result.Contradiction = "the only dominoes that fit break a condition"
+			return result
+		}
+		result.Solution = &solution
+		return result
+	}
+
+	// nothing applies - every deduction so far holds in every solution, so any solution will agree with them
+	opts.MaxSolutions = 1
+	search := Search(ctx, game, opts)
+	result.UsedSearch = true
+	result.StopReason = search.StopReason
+	if len(search.Solutions) == 0 {
+		if search.Exhaustive() {
+			result.Contradiction = "no technique applies and searching found no solution"
+		}
+		return result
+	}
+	solution := search.Solutions[0]
+	result.Solution = &solution
+	remaining := make([]*cell, 0)
+	for _, c := range game.cells {
+		if !state.values.filled.has(c.index) {
+			remaining = append(remaining, c)
+		}
+	}
+	result.Steps = append(result.Steps, DeductionStep{
+		Technique:  TechniqueSearch,
+		Cells:      cellIdentifiers(remaining),
+		Conclusion: fmt.Sprintf("the last %d cells were filled by searching", len(remaining)),
+		Reason:     "No technique applies to what is left, so the rest had to be found by trial and error.",
+	})
+	return result
+}
+
+// deductionState - what has been deduced about a game so far
+type deductionState struct {
+	game       *Game
+	domains    cellDomains // values each cell could still hold
+	partner    []int       // index of the cell sharing a domino with each cell, -1 while unknown
+	values     *boardValues
+	unplaced   dominoInventory // dominoes not placed yet
+	placements []DominoPlacement
+}
+
+// starts from nothing but the values on the dominoes
+func newDeductionState(g *Game) *deductionState {
+	dominoValues := valueDomain(0)
+	for _, d := range g.dominoes {
+		dominoValues |= singleValueDomain(d.val1) | singleValueDomain(d.val2)
+	}
+	s := &deductionState{
+		game:     g,
+		domains:  make(cellDomains, len(g.cells)),
+		partner:  make([]int, len(g.cells)),
+		values:   newBoardValues(len(g.cells)),
+		unplaced: g.newDominoInventory(),
+	}
+	for i := range s.domains {
+		s.domains[i] = dominoValues
+		s.partner[i] = -1
+	}
+	return s
+}
+
+func (s *deductionState) solved() bool {
+	return s.values.filled.containsAll(s.game.inPlayCells)
+}
+
+// finds and applies the easiest step that deduces something new, returning nil if no technique applies, or why the
+// state is impossible if a technique finds a contradiction
+func (s *deductionState) nextStep() (*DeductionStep, string) {
+	for _, t := range techniques {
+		var step *DeductionStep
+		var contradiction string
+		switch t {
+		case TechniqueDeadEndPairing:
+			step, contradiction = s.deadEndPairing()
+		case TechniqueDominoValues:
+			step, contradiction = s.dominoValues()
+		case TechniqueSumBounds, TechniqueEqualRegion, TechniqueDistinctPigeonhole:
+			step, contradiction = s.regionStep(t)
+		case TechniqueOnlyFittingDomino:
+			step, contradiction = s.onlyFittingDomino()
+		case TechniqueLastDominoOfAKind:
+			step, contradiction = s.lastDominoOfAKind()
+		case TechniqueSearch:
+		default:
+			panic("unhandled technique")
+		}
+		if contradiction != "" || step != nil {
+			return step, contradiction
+		}
+	}
+	return nil, ""
+}
+
+// a domino placed in a specific spot - cell1 and cell2 are neighbors
+type deductionPlacement struct {
+	cell1, cell2 *cell
+	kindIdx      int
+	values       [2]int
+}
+
+// every way left of placing a domino on a cell and one of its neighbors, given what is known so far
+func (s *deductionState) placementsFor(c, neighbor *cell) []deductionPlacement {
+	if s.values.filled.has(c.index) || s.values.filled.has(neighbor.index) {
+		return nil
+	}
+	if s.partner[c.index] != -1 && s.partner[c.index] != neighbor.index {
+		return nil
+	}
+	if s.partner[neighbor.index] != -1 && s.partner[neighbor.index] != c.index {
+		return nil
+	}
+	placements := make([]deductionPlacement, 0)
+	for kindIdx, kind := range s.game.dominoKinds {
+		if s.unplaced[kindIdx] == 0 {
+			continue
+		}
+		for _, o := range kind.orientations() {
+			if s.domains[c.index].has(o[0]) && s.domains[neighbor.index].has(o[1]) {
+				placements = append(placements, deductionPlacement{cell1: c, cell2: neighbor, kindIdx: kindIdx, values: o})
+			}
+		}
+	}
+	return placements
+}
+
+// fills in a domino, fixing its cells' values
+func (s *deductionState) place(p deductionPlacement) {
+	s.partner[p.cell1.index] = p.cell2.index
+	s.partner[p.cell2.index] = p.cell1.index
+	s.values.set(p.cell1.index, p.values[0])
+	s.values.set(p.cell2.index, p.values[1])
+	s.domains[p.cell1.index] = singleValueDomain(p.values[0])
+	s.domains[p.cell2.index] = singleValueDomain(p.values[1])
+	s.unplaced[p.kindIdx]--
+	s.placements = append(s.placements, DominoPlacement{
+		cell1:       p.cell1,
+		cell1Value:  p.values[0],
+		cell2:       p.cell2,
+		cell2Value:  p.values[1],
+		printString: s.game.dominoKinds[p.kindIdx].String(),
+	})
+}
+
+// pairs a cell that has only one neighbor left that it could share a domino with
+func (s *deductionState) deadEndPairing() (*DeductionStep, string) {
+	for _, c := range s.game.cells {
+		if s.partner[c.index] != -1 {
+			continue
+		}
+		free, fitting := 0, make([]*cell, 0, 1)
+		for _, neighbor := range c.neighbors() {
+			if s.partner[neighbor.index] != -1 {
+				continue
+			}
+			free++
+			if len(s.placementsFor(c, neighbor)) > 0 {
+				fitting = append(fitting, neighbor)
+			}
+		}
+		switch len(fitting) {
+		case 0:
+			return nil, fmt.Sprintf("cell %s has no neighbor left that it could share a domino with", c)
+		case 1:
+			neighbor := fitting[0]
+			s.partner[c.index] = neighbor.index
+			s.partner[neighbor.index] = c.index
+			reason := fmt.Sprintf("%s is the only neighbor of %s that isn't already covered", neighbor, c)
+			if free > 1 {
+				reason = fmt.Sprintf("%s is the only free neighbor of %s that any domino left fits with", neighbor, c)
+			}
+			return &DeductionStep{
+				Technique:  TechniqueDeadEndPairing,
+				Cells:      cellIdentifiers([]*cell{c, neighbor}),
+				Conclusion: fmt.Sprintf("cells %s must be covered by the same domino", describePair(c, neighbor)),
+				Reason:     capitalize(reason) + ".",
+			}, ""
+		}
+	}
+	return nil, ""
+}
+
+// narrows a cell to the values found on the dominoes that could still cover it
+func (s *deductionState) dominoValues() (*DeductionStep, string) {
+	for _, c := range s.game.cells {
+		if s.values.filled.has(c.index) {
+			continue
+		}
+		possible := valueDomain(0)
+		for _, neighbor := range c.neighbors() {
+			for _, p := range s.placementsFor(c, neighbor) {
+				possible |= singleValueDomain(p.values[0])
+			}
+		}
+		narrowed := s.domains[c.index] & possible
+		if narrowed == s.domains[c.index] {
+			continue
+		}
+		if narrowed == 0 {
+			return nil, fmt.Sprintf("no domino left can cover cell %s", c)
+		}
+		s.domains[c.index] = narrowed
+		reason := "The dominoes left that could cover it with a neighbor only have those values."
+		if s.partner[c.index] != -1 {
+			reason = fmt.Sprintf("It shares a domino with %s, and the dominoes left that fit there only have those values.", s.game.cells[s.partner[c.index]])
+		}
+		return &DeductionStep{
+			Technique:  TechniqueDominoValues,
+			Cells:      cellIdentifiers([]*cell{c}),
+			Conclusion: describeCellDomain(c, narrowed),
+			Reason:     reason,
+		}, ""
+	}
+	return nil, ""
+}
+
+// narrows the cells of a region with the condition technique matching its expression
+func (s *deductionState) regionStep(t Technique) (*DeductionStep, string) {
+	for _, cond := range s.game.conditions {
+		var reason string
+		switch {
+		case t == TechniqueSumBounds && (cond.expression == conditionExpSumEquals || cond.expression == conditionExpSumLessThan || cond.expression == conditionExpSumGreaterThan):
+			reason = "given the smallest and largest values the rest of the region could hold"
+		case t == TechniqueEqualRegion && cond.expression == conditionExpEquivalent:
+			reason = "so every cell can only hold values all of them could hold"
+		case t == TechniqueDistinctPigeonhole && cond.expression == conditionExpDistinct:
+			reason = "so values already taken in the region are ruled out for the rest of it"
+		default:
+			continue
+		}
+
+		if len(cond.cells) == 1 {
+			reason = "so it can only hold values that meet it"
+		}
+
+		// work the region out in full on a copy, but only take the first cell it narrows, so every step is one fact -
+		// the rest come up again on the next steps
+		narrowed := slices.Clone(s.domains)
+		changed, ok := cond.propagate(s.values, narrowed)
+		if !ok {
+			return nil, fmt.Sprintf(`"%s" can no longer be met`, cond)
+		}
+		if !changed {
+			continue
+		}
+		for _, cellIndex := range cond.cells {
+			if narrowed[cellIndex] == s.domains[cellIndex] {
+				continue
+			}
+			c := s.game.cells[cellIndex]
+			s.domains[cellIndex] = narrowed[cellIndex]
+			return &DeductionStep{
+				Technique:  t,
+				Cells:      cellIdentifiers([]*cell{c}),
+				Conclusion: describeCellDomain(c, narrowed[cellIndex]),
+				Reason:     fmt.Sprintf("%s, %s.", cond, reason),
+			}, ""
+		}
+	}
+	return nil, ""
+}
+
+// places the domino in a pair of cells known to share one, when only one domino fits there
+func (s *deductionState) onlyFittingDomino() (*DeductionStep, string) {
+	for _, c := range s.game.cells {
+		p := s.partner[c.index]
+		if p == -1 || p < c.index || s.values.filled.has(c.index) {
+			continue
+		}
+		neighbor := s.game.cells[p]
+		placements := s.placementsFor(c, neighbor)
+		switch len(placements) {
+		case 0:
+			return nil, fmt.Sprintf("no domino left fits cells %s", describePair(c, neighbor))
+		case 1:
+			s.place(placements[0])
+			return &DeductionStep{
+				Technique:  TechniqueOnlyFittingDomino,
+				Cells:      cellIdentifiers([]*cell{c, neighbor}),
+				Conclusion: describePlacement(s.game, placements[0]),
+				Reason:     fmt.Sprintf("Cells %s share a domino, and it is the only one left that fits the values they can hold.", describePair(c, neighbor)),
+			}, ""
+		}
+	}
+	return nil, ""
+}
+
+// places the last domino of a kind when there is only one place left for it
+func (s *deductionState) lastDominoOfAKind() (*DeductionStep, string) {
+	for kindIdx, kind := range s.game.dominoKinds {
+		if s.unplaced[kindIdx] == 0 {
+			continue
+		}
+		fits := make([]deductionPlacement, 0)
+		for _, c := range s.game.cells {
+			for _, neighbor := range []*cell{c.neighborRight, c.neighborBelow} {
+				if neighbor == nil {
+					continue
+				}
+				for _, p := range s.placementsFor(c, neighbor) {
+					if p.kindIdx == kindIdx {
+						fits = append(fits, p)
+					}
+				}
+			}
+		}
+		if len(fits) < s.unplaced[kindIdx] {
+			return nil, fmt.Sprintf("there aren't enough places left for every %s domino", kind)
+		}
+		if s.unplaced[kindIdx] == 1 && len(fits) == 1 {
+			p := fits[0]
+			s.place(p)
+			return &DeductionStep{
+				Technique:  TechniqueLastDominoOfAKind,
+				Cells:      cellIdentifiers([]*cell{p.cell1, p.cell2}),
+				Conclusion: describePlacement(s.game, p),
+				Reason:     fmt.Sprintf("%s is the last domino of its kind, and that is the only place left it fits.", kind),
+			}, ""
+		}
+	}
+	return nil, ""
+}
+
+// the in-play neighbors of a cell
+func (c *cell) neighbors() []*cell {
+	neighbors := make([]*cell, 0, 4)
+	for _, neighbor := range []*cell{c.neighborRight, c.neighborBelow, c.neighborLeft, c.neighborAbove} {
+		if neighbor != nil {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return neighbors
+}
+
+// identifiers of cells, in reading order
+func cellIdentifiers(cells []*cell) []string {
+	sorted := slices.Clone(cells)
+	slices.SortFunc(sorted, func(l, r *cell) int {
+		return l.index - r.index
+	})
+	identifiers := make([]string, 0, len(sorted))
+	for _, c := range sorted {
+		identifiers = append(identifiers, c.identifier())
+	}
+	return identifiers
+}
+
+// e.g. "0:0-0:1", in reading order
+func describePair(c1, c2 *cell) string {
+	if c1.index > c2.index {
+		c1, c2 = c2, c1
+	}
+	return c1.identifier() + "-" + c2.identifier()
+}
+
+// e.g. "cell 3:4 must be 6" or "cell 3:4 must be 4, 5 or 6"
+func describeCellDomain(c *cell, d valueDomain) string {
+	values := make([]string, 0, d.size())
+	for v := minPipValue; v <= maxPipValue; v++ {
+		if d.has(v) {
+			values = append(values, strconv.Itoa(v))
+		}
+	}
+	if len(values) == 1 {
+		return fmt.Sprintf("cell %s must be %s", c, values[0])
+	}
+	return fmt.Sprintf("cell %s must be %s or %s", c, strings.Join(values[:len(values)-1], ", "), values[len(values)-1])
+}
+
+// e.g. "cells 0:0-0:1 must hold [5|5] as 5 & 5"
+func describePlacement(g *Game, p deductionPlacement) string {
+	return fmt.Sprintf(
+		"cells %s-%s must hold %s as %d & %d",
+		p.cell1, p.cell2, g.dominoKinds[p.kindIdx], p.values[0], p.values[1],
+	)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,252 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Dancing Links (Knuth's Algorithm X) model of the puzzle as an exact cover problem:
+//   - every in-play cell is a column that must be covered exactly once
+//   - every kind of domino is a column that can be covered as many times as there are dominoes of that kind
+//   - every (location, domino kind, orientation) is a row covering its two cells and its domino kind
+//
+// Domino kind columns are never branched on. They only get covered (removing their rows) once every domino of
+// the kind is used. Since there are exactly two cells per domino, covering every cell uses up every domino.
+//
+// Conditions are not columns. They are checked as secondary constraints whenever a row is selected, so a
+// row whose values break an already-complete condition is never explored.
+
+// a node in the toroidal doubly linked list - column headers are nodes too
+type dlxNode struct {
+	left, right, up, down *dlxNode
+	column                *dlxNode // the header of the column this node is in
+	row                   *dlxRow  // nil for column headers
+	// only used by column headers
+	size      int    // number of rows currently in the column
+	label     string // for debug printing
+	counted   bool   // domino kind columns can be used more than once before they are covered
+	remaining int    // for counted columns, the number of times the column can still be used
+}
+
+// the placement a row stands for
+type dlxRow struct {
+	cell1, cell2 *cell
+	domino       dominoKind
+	val1, val2   int // values of cell1 and cell2 respectively
+}
+
+// GetSolutionsExactCover - finds valid solutions by solving the puzzle as an exact cover problem with Dancing Links.
+// This is an alternative engine to the arrangement-then-fill pipeline and can be run on the same Game.
+// Stops early if the context is cancelled or the budget runs out.
+func GetSolutionsExactCover(ctx context.Context, game *Game, budget *SearchBudget, outSolutions chan<- Solution) {
+	if game == nil {
+		panic("nil game")
+	}
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Calculating solutions with exact cover (Dancing Links)...")
+
+	if len(game.cells) != 2*len(game.dominoes) {
+		debugPrint(fmt.Println, "Number of cells does not match the number of dominoes - no exact cover is possible...")
+		return
+	}
+
+	root := buildDLXMatrix(game)
+	conditions := newConditionTracker(game)
+	selectedRows := make([]*dlxRow, 0, len(game.dominoes))
+
+	searchDLX(ctx, budget, root, conditions, selectedRows, outSolutions)
+}
+
+// builds the exact cover matrix for a game, returning the root header
+func buildDLXMatrix(game *Game) *dlxNode {
+	root := &dlxNode{label: "root"}
+	root.left, root.right = root, root
+
+	addColumn := func(label string) *dlxNode {
+		col := &dlxNode{label: label}
+		col.up, col.down, col.column = col, col, col
+		col.left, col.right = root.left, root
+		root.left.right = col
+		root.left = col
+		return col
+	}
+
+	// one column per in-play cell by cell index, in reading order so the debug output is predictable
+	cellColumns := make([]*dlxNode, len(game.cells))
+	for _, c := range game.cells {
+		cellColumns[c.index] = addColumn("cell " + c.identifier())
+	}
+
+	// one column per kind of domino, kept out of the header list so they are never chosen to branch on
+	dominoColumns := make(map[dominoKind]*dlxNode)
+	for kindIdx, count := range game.newDominoInventory() {
+		kind := game.dominoKinds[kindIdx]
+		col := &dlxNode{label: "domino " + kind.String(), counted: true, remaining: count}
+		col.up, col.down, col.column = col, col, col
+		col.left, col.right = col, col
+		dominoColumns[kind] = col
+	}
+
+	addRow := func(r *dlxRow) {
+		var first *dlxNode
+		for _, col := range []*dlxNode{cellColumns[r.cell1.index], cellColumns[r.cell2.index], dominoColumns[r.domino]} {
+			n := &dlxNode{column: col, row: r}
+			// vertical link at the bottom of the column
+			n.up, n.down = col.up, col
+			col.up.down = n
+			col.up = n
+			col.size++
+			// horizontal link at the end of the row
+			if first == nil {
+				first = n
+				n.left, n.right = n, n
+			} else {
+				n.left, n.right = first.left, first
+				first.left.right = n
+				first.left = n
+			}
+		}
+	}
+
+	// one row per location, domino kind, and orientation - only looking right and down so each location is added once
+	for _, c := range game.cells {
+		for _, neighbor := range []*cell{c.neighborRight, c.neighborBelow} {
+			if neighbor == nil {
+				continue
+			}
+			for _, kind := range game.dominoKinds {
+				for _, o := range kind.orientations() {
+					addRow(&dlxRow{cell1: c, cell2: neighbor, domino: kind, val1: o[0], val2: o[1]})
+				}
+			}
+		}
+	}
+
+	return root
+}
+
+// removes a column and every row that intersects it from the matrix
+func (col *dlxNode) cover() {
+	col.right.left = col.left
+	col.left.right = col.right
+	for r := col.down; r != col; r = r.down {
+		for n := r.right; n != r; n = n.right {
+			n.down.up = n.up
+			n.up.down = n.down
+			n.column.size--
+		}
+	}
+}
+
+// exactly reverses cover
+func (col *dlxNode) uncover() {
+	for r := col.up; r != col; r = r.up {
+		for n := r.left; n != r; n = n.left {
+			n.column.size++
+			n.down.up = n
+			n.up.down = n
+		}
+	}
+	col.right.left = col
+	col.left.right = col
+}
+
+// covers a column used by a selected row - domino kind columns are only covered once the last domino of the kind
+// is used
+func (col *dlxNode) use() {
+	if col.counted {
+		col.remaining--
+		if col.remaining > 0 {
+			return
+		}
+	}
+	col.cover()
+}
+
+// exactly reverses use
+func (col *dlxNode) unuse() {
+	if col.counted {
+		col.remaining++
+		if col.remaining > 1 {
+			return
+		}
+	}
+	col.uncover()
+}
+
+// recursively selects rows until every column is covered
+func searchDLX(
+	ctx context.Context,
+	budget *SearchBudget,
+	root *dlxNode,
+	conditions *conditionTracker,
+	selectedRows []*dlxRow,
+	outSolutions chan<- Solution,
+) {
+	if outSolutions == nil {
+		panic("nil output solutions")
+	}
+	if ctx.Err() != nil || !budget.spendNode() {
+		return
+	}
+
+	// base case - every cell and domino is covered exactly once
+	if root.right == root {
+		placements := make([]DominoPlacement, 0, len(selectedRows))
+		for _, r := range selectedRows {
+			placements = append(placements, DominoPlacement{
+				cell1:       r.cell1,
+				cell1Value:  r.val1,
+				cell2:       r.cell2,
+				cell2Value:  r.val2,
+				printString: r.domino.String(),
+			})
+		}
+		if sendOrCancel(ctx, outSolutions, Solution{dominoPlacements: placements}) {
+			debugPrint(fmt.Println, "All columns covered and solution added...")
+		}
+		return
+	}
+
+	// pick the column with the fewest rows left to keep the search narrow
+	col := root.right
+	for c := col.right; c != root; c = c.right {
+		if c.size < col.size {
+			col = c
+		}
+	}
+	if col.size == 0 {
+		debugPrint(fmt.Printf, "Column %s can no longer be covered - backtracking...\n", col.label)
+		return
+	}
+
+	col.cover()
+	for r := col.down; r != col; r = r.down {
+		row := r.row
+
+		// secondary constraints - the conditions on the two cells must still hold with the row's values
+		conditions.fill(row.cell1, row.val1)
+		conditions.fill(row.cell2, row.val2)
+		if conditions.holds(row.cell1) && conditions.holds(row.cell2) {
+			debugPrint(fmt.Printf, "selecting domino %s in cells %s & %s...\n", row.domino, row.cell1, row.cell2)
+
+			for n := r.right; n != r; n = n.right {
+				n.column.use()
+			}
+			selectedRows = append(selectedRows, row)
+
+			searchDLX(ctx, budget, root, conditions, selectedRows, outSolutions)
+
+			// backtrack
+			selectedRows = selectedRows[0 : len(selectedRows)-1]
+			for n := r.left; n != r; n = n.left {
+				n.column.unuse()
+			}
+		}
+		conditions.unfill(row.cell1, row.val1)
+		conditions.unfill(row.cell2, row.val2)
+	}
+	col.uncover()
+}
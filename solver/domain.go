@@ -0,0 +1,128 @@
+package solver
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// valueDomain - the set of pip values (0-6) a cell could still hold, stored as a bitmask
+type valueDomain uint8
+
+const (
+	minPipValue = 0
+	maxPipValue = 6
+	// every pip value is possible
+	fullValueDomain valueDomain = 1<<(maxPipValue+1) - 1
+)
+
+// domain containing only a single value
+func singleValueDomain(v int) valueDomain {
+	return 1 << v
+}
+
+func (d valueDomain) has(v int) bool {
+	return v >= minPipValue && v <= maxPipValue && d&(1<<v) != 0
+}
+
+func (d valueDomain) size() int {
+	return bits.OnesCount8(uint8(d))
+}
+
+// smallest value in the domain, only meaningful for non-empty domains
+func (d valueDomain) min() int {
+	return bits.TrailingZeros8(uint8(d))
+}
+
+// largest value in the domain, only meaningful for non-empty domains
+func (d valueDomain) max() int {
+	return 7 - bits.LeadingZeros8(uint8(d))
+}
+
+// keeps only the values between lo and hi (inclusive)
+func (d valueDomain) clamp(lo, hi int) valueDomain {
+	lo = max(lo, minPipValue)
+	hi = min(hi, maxPipValue)
+	if lo > hi {
+		return 0
+	}
+	return d & (fullValueDomain >> (maxPipValue - hi)) &^ (1<<lo - 1)
+}
+
+func (d valueDomain) String() string {
+	values := make([]string, 0, d.size())
+	for v := minPipValue; v <= maxPipValue; v++ {
+		if d.has(v) {
+			values = append(values, strconv.Itoa(v))
+		}
+	}
+	return "{" + strings.Join(values, ",") + "}"
+}
+
+// cellDomains - the domains of every in-play cell during a search, indexed by cell index
+type cellDomains []valueDomain
+
+// narrows the domain of a cell, returning false if the cell is left with no possible values
+func (cd cellDomains) narrow(cellIndex int, d valueDomain) (changed bool, ok bool) {
+	old := cd[cellIndex]
+	narrowed := old & d
+	cd[cellIndex] = narrowed
+	return narrowed != old, narrowed != 0
+}
+
+// propagateFromCells - like propagateConditions, but only starts from the conditions on the given cells, and only
+// revisits another condition once a condition sharing one of its cells has narrowed something. The domains have to
+// already be propagated apart from the given cells.
+func (g *Game) propagateFromCells(cellValues *boardValues, domains cellDomains, cells ...*cell) bool {
+	queued := make([]bool, len(g.conditions))
+	queue := make([]*condition, 0, len(g.conditions))
+	enqueue := func(c *cell) {
+		for _, cond := range c.applicableConditions {
+			if !queued[cond.index] {
+				queued[cond.index] = true
+				queue = append(queue, cond)
+			}
+		}
+	}
+	for _, c := range cells {
+		enqueue(c)
+	}
+
+	for len(queue) > 0 {
+		cond := queue[0]
+		queue = queue[1:]
+		queued[cond.index] = false
+
+		changed, ok := cond.propagate(cellValues, domains)
+		if !ok {
+			debugPrint(fmt.Printf, `Propagation proved "%s" can no longer be met`+"\n", cond)
+			return false
+		}
+		if changed {
+			for _, cellIndex := range cond.cells {
+				enqueue(g.cells[cellIndex])
+			}
+		}
+	}
+	return true
+}
+
+// propagateConditions - repeatedly narrows the domains of unfilled cells using every condition until nothing changes.
+// Returns false as soon as a condition can no longer be satisfied by any values left in the domains.
+func propagateConditions(conditions []*condition, cellValues *boardValues, domains cellDomains) bool {
+	for {
+		changed := false
+		for _, cond := range conditions {
+			condChanged, ok := cond.propagate(cellValues, domains)
+			if !ok {
+				debugPrint(fmt.Printf, `Propagation proved "%s" can no longer be met`+"\n", cond)
+				return false
+			}
+			changed = changed || condChanged
+		}
+		if !changed {
+			return true
+		}
+	}
+}
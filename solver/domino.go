@@ -4,20 +4,94 @@ import (
 	"djlovell/nyt_pips_solver/input"
 	"errors"
 	"fmt"
-
-	"github.com/google/uuid"
+	"math/bits"
 )
 
 type domino struct {
-	identifier string // in case we need to uniquely identify these
-	val1       int
-	val2       int
+	val1 int
+	val2 int
 }
 
 func (d domino) String() string {
 	return fmt.Sprintf("[%d|%d]", d.val1, d.val2)
 }
 
+// the kind of domino this is - two dominoes with the same values are interchangeable no matter their polarity
+func (d domino) kind() dominoKind {
+	return dominoKind{low: min(d.val1, d.val2), high: max(d.val1, d.val2)}
+}
+
+// dominoKind - identifies interchangeable dominoes by their values, lowest first
+type dominoKind struct {
+	low, high int
+}
+
+func (k dominoKind) String() string {
+	return fmt.Sprintf("[%d|%d]", k.low, k.high)
+}
+
+func (k dominoKind) isDouble() bool {
+	return k.low == k.high
+}
+
+// the ways the domino's values can be laid into a location's two cells - doubles only have one
+func (k dominoKind) orientations() [][2]int {
+	if k.isDouble() {
+		return [][2]int{{k.low, k.high}}
+	}
+	return [][2]int{{k.low, k.high}, {k.high, k.low}}
+}
+
+// dominoKindSet - a set of domino kinds stored as a bitmask over their indices in Game.dominoKinds. There are only
+// 28 kinds of domino with values 0-6, so they all fit.
+type dominoKindSet uint32
+
+func (s dominoKindSet) has(kindIdx int) bool {
+	return s&(1<<kindIdx) != 0
+}
+
+func (s *dominoKindSet) add(kindIdx int) {
+	*s |= 1 << kindIdx
+}
+
+func (s dominoKindSet) size() int {
+	return bits.OnesCount32(uint32(s))
+}
+
+// dominoInventory - the dominoes left to place as a multiset, holding the number left of each kind in
+// Game.dominoKinds (by index)
+//
+// Searching by kind instead of by individual domino means identical dominoes are only ever tried once per location,
+// so the search never produces the same board twice by swapping them.
+type dominoInventory []int
+
+// creates the full inventory for a game, before any dominoes are placed
+func (g *Game) newDominoInventory() dominoInventory {
+	inv := make(dominoInventory, len(g.dominoKinds))
+	for _, d := range g.dominoes {
+		inv[g.dominoKindIndex[d.kind()]]++
+	}
+	return inv
+}
+
+// total number of dominoes left
+func (inv dominoInventory) total() int {
+	total := 0
+	for _, count := range inv {
+		total += count
+	}
+	return total
+}
+
+// identifies the inventory by its counts, for using as a map key
+func (inv dominoInventory) key() string {
+	b := make([]byte, len(inv))
+	for i, count := range inv {
+		b[i] = byte(count)
+	}
+	return string(b)
+}
+
 // parses a domino from an input specification
 func parseInputDomino(d *input.Domino) (*domino, error) {
 	if d == nil {
@@ -34,8 +108,7 @@ func parseInputDomino(d *input.Domino) (*domino, error) {
 		return nil, errors.New("domino values must be between 0 and 6")
 	}
 	return &domino{
-		identifier: uuid.NewString(),
-		val1:       val1,
-		val2:       val2,
+		val1: val1,
+		val2: val2,
 	}, nil
 }
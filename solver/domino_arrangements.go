@@ -1,68 +1,84 @@
 package solver
 
 import (
+	"context"
 	"fmt"
-	"maps"
 	"slices"
 	"strings"
 )
 
 // DominoArrangementLocation - defines a grouping of cells where a domino could be placed based on which cells are in play
 type DominoArrangementLocation struct {
-	cell1 string // identifier
-	cell2 string // identifier
+	cell1 *cell
+	cell2 *cell
 	// potential optimization - pre-determine dominoes that can't go in this location
-	blacklistedDominoIDs *map[string]any
+	blacklistedDominoKinds dominoKindSet
 }
 
 func (a DominoArrangementLocation) String() string {
-	identifiers := []string{a.cell1, a.cell2}
+	identifiers := []string{a.cell1.identifier(), a.cell2.identifier()}
 	slices.Sort(identifiers)
 	return fmt.Sprintf("Cells %s-%s\n", identifiers[0], identifiers[1])
 }
 
 // experiment - filter down dominoes that can go in this location for later checking
-func (a *DominoArrangementLocation) addBlacklistedDominoIDs(g *Game) *DominoArrangementLocation {
-	conditionsForLocation := append(
-		g.inPlayCellsByIdentifier[a.cell1].applicableConditions,
-		g.inPlayCellsByIdentifier[a.cell2].applicableConditions...,
-	)
-	invalidDominoes := make(map[string]any)
-	for _, d := range g.dominoes {
+func (a *DominoArrangementLocation) addBlacklistedDominoKinds(g *Game) *DominoArrangementLocation {
+	// concat into a new slice - appending to the cell's own slice could write into memory shared across goroutines
+	conditionsForLocation := slices.Concat(a.cell1.applicableConditions, a.cell2.applicableConditions)
+	cell1Domain := a.cell1.domain
+	cell2Domain := a.cell2.domain
+	invalidDominoes := dominoKindSet(0)
+	for kindIdx, d := range g.dominoKinds {
+		// neither orientation of the domino fits the values the cells could hold
+		fits := (cell1Domain.has(d.low) && cell2Domain.has(d.high)) || (cell1Domain.has(d.high) && cell2Domain.has(d.low))
+		if !fits {
+			invalidDominoes.add(kindIdx)
+			debugPrint(fmt.Printf, "Domino %s blacklisted for location %s\n", d.String(), a.String())
+		}
 		for _, c := range conditionsForLocation {
 			// if both values in a domino fail a condition...blacklist the domino
 			switch c.expression {
 			case conditionExpSumEquals:
 				// both domino values exceed
-				if d.val1 > c.operand && d.val2 > c.operand {
-					invalidDominoes[d.identifier] = true
+				if d.low > c.operand && d.high > c.operand {
+					invalidDominoes.add(kindIdx)
 					debugPrint(fmt.Printf, "Domino %s blacklisted for location %s\n", d.String(), a.String())
 				}
 			case conditionExpSumLessThan:
 				// both domino values meet or exceed
-				if d.val1 >= c.operand && d.val2 >= c.operand {
-					invalidDominoes[d.identifier] = true
+				if d.low >= c.operand && d.high >= c.operand {
+					invalidDominoes.add(kindIdx)
 					debugPrint(fmt.Printf, "Domino %s blacklisted for location %s\n", d.String(), a.String())
 				}
 			case conditionExpSumGreaterThan:
 				// the condition only uses one cell and neither domino value is sufficient
 				if len(c.cellIdentifiers) == 1 {
-					if d.val1 <= c.operand && d.val2 <= c.operand {
-						invalidDominoes[d.identifier] = true
+					if d.low <= c.operand && d.high <= c.operand {
+						invalidDominoes.add(kindIdx)
 						debugPrint(fmt.Printf, "Domino %s blacklisted for location %s\n", d.String(), a.String())
 					}
 				}
 			case conditionExpEquivalent:
-				// harder to check for without visiting other cells
+				// both cells share the condition, so the domino has to be a double
+				if c.cellMask.has(a.cell1.index) && c.cellMask.has(a.cell2.index) && !d.isDouble() {
+					invalidDominoes.add(kindIdx)
+					debugPrint(fmt.Printf, "Domino %s blacklisted for location %s\n", d.String(), a.String())
+				}
+				// anything else depends on the cell domains checked above
 			case conditionExpDistinct:
-				// harder to check for without visiting other cells
+				// both cells share the condition, so the domino can't be a double
+				if c.cellMask.has(a.cell1.index) && c.cellMask.has(a.cell2.index) && d.isDouble() {
+					invalidDominoes.add(kindIdx)
+					debugPrint(fmt.Printf, "Domino %s blacklisted for location %s\n", d.String(), a.String())
+				}
+				// anything else depends on the cell domains checked above
 			default:
 				panic("unhandled condition expression type")
 			}
 		}
 	}
 
-	a.blacklistedDominoIDs = &invalidDominoes
+	a.blacklistedDominoKinds = invalidDominoes
 	return a
 }
 
@@ -80,8 +96,9 @@ func (a DominoArrangement) String() string {
 }
 
 // GetDominoArrangements - determines possible arrangements for laying dominoes on a board.
-// Pre-computing valid domino positions will simplify solving later.
-func GetDominoArrangements(game *Game, outArrangements chan<- DominoArrangement) {
+// Pre-computing valid domino positions will simplify solving later. Stops early if the context is cancelled or the
+// budget runs out.
+func GetDominoArrangements(ctx context.Context, game *Game, budget *SearchBudget, outArrangements chan<- DominoArrangement) {
 	if game == nil {
 		panic("nil board")
 	}
@@ -89,87 +106,170 @@ func GetDominoArrangements(game *Game, outArrangements chan<- DominoArrangement)
 	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
 	debugPrint(fmt.Println, "Calculating possible arrangements for dominoes on the board...")
 
-	// create a map of played cells to track which ones have been included in arrangements
-	cellsRemaining := make(map[string]*cell)
-	maps.Copy(cellsRemaining, game.inPlayCellsByIdentifier)
+	// track which played cells have not been included in the arrangement yet
+	cellsRemaining := game.inPlayCells
 
 	locations := make([]DominoArrangementLocation, 0) // tracks locations of fitted dominoes for a possible arrangement
 
 	// start finding arrangements
-	findDominoArrangements(game, cellsRemaining, locations, outArrangements)
+	findDominoArrangements(ctx, game, budget, nil, cellsRemaining, locations, newDominoMatching(game), func(_ *schedulerWorker, a DominoArrangement) {
+		if sendOrCancel(ctx, outArrangements, a) {
+			debugPrint(fmt.Println, "All cells accounted for and arrangement added...")
+		}
+	})
+	debugPrint(fmt.Printf, "Tiling placed %d locations by forced moves and %d by branching\n", budget.ForcedLocations(), budget.BranchedLocations())
 }
 
 // attempts to recurse through different ways of fitting dominoes to a board without using loops
 // each recursive call will fit a domino into a cell and one of its neighbors, then remove the two from the remaining cells
+//
+// cells with only one free neighbor are paired with it straight away without branching, and otherwise the cell with
+// the fewest free neighbors is branched on, so dead ends (cells with no free neighbors) show up as early as possible
+//
+// partial tilings are abandoned as soon as their locations can't each be given a different domino that isn't
+// blacklisted for it, since no way of finishing them could be filled either
+//
+// complete arrangements are handed to emit, along with the worker that found them (nil without a scheduler)
 func findDominoArrangements(
+	ctx context.Context,
 	game *Game,
-	unarrangedCells map[string]*cell,
+	budget *SearchBudget,
+	worker *schedulerWorker,
+	unarrangedCells cellSet,
 	locations []DominoArrangementLocation,
-	outArrangements chan<- DominoArrangement,
+	matching *dominoMatching, // matches locations to dominoes
+	emit func(*schedulerWorker, DominoArrangement),
 ) {
 	if game == nil {
 		panic("nil board")
 	}
-	if outArrangements == nil {
-		panic("nil output arrangements")
+	if emit == nil {
+		panic("nil arrangement handler")
+	}
+	if ctx.Err() != nil || !budget.spendNode() {
+		return
+	}
+
+	// place the forced dominoes first - a cell with only one free neighbor has to pair with it, which can leave
+	// another cell with only one, and so on. Anything placed here is taken back off the matching on the way out.
+	forced := 0
+	defer func() {
+		for range forced {
+			matching.pop()
+		}
+	}()
+	var nextCell *cell // the cell with the fewest free neighbors, to branch on once nothing is forced
+	for {
+		nextCell = nil
+		nextDegree := 0
+		for _, c := range game.cells {
+			if !unarrangedCells.has(c.index) {
+				continue
+			}
+			if degree := c.freeNeighbors(unarrangedCells); nextCell == nil || degree < nextDegree {
+				nextCell, nextDegree = c, degree
+			}
+			if nextDegree == 0 {
+				break
+			}
+		}
+
+		// if at any point we encounter a cell that has no remaining neighbors that aren't accounted for...we have ran
+		// into an invalid fitment
+		if nextCell != nil && nextDegree == 0 {
+			debugPrint(fmt.Printf, "Attempted arrangement resulted in an orphaned cell %s - %d cells unarranged...\n", nextCell, unarrangedCells.size())
+			return
+		}
+		if nextCell == nil || nextDegree > 1 {
+			break
+		}
+
+		neighbor := nextCell.firstFreeNeighbor(unarrangedCells)
+		forcedLocation := game.newLocation(nextCell, neighbor)
+		if !matching.push(forcedLocation) {
+			debugPrint(fmt.Printf, "Abandoning tiling - no dominoes left for forced location %s", forcedLocation)
+			budget.countPrunedTiling()
+			return
+		}
+		forced++
+		budget.countForcedLocation()
+		locations = append(locations, forcedLocation)
+		unarrangedCells.remove(nextCell.index)
+		unarrangedCells.remove(neighbor.index)
 	}
 
 	// base case - all cells have been accounted for in the arrangement, so save it
-	if len(unarrangedCells) == 0 {
+	if unarrangedCells.isEmpty() {
 		// need to do this copy to prevent backtracking bugs (if using)
 		locationsCopy := make([]DominoArrangementLocation, len(locations))
 		copy(locationsCopy, locations)
 		newSolution := DominoArrangement{
 			locations: locationsCopy,
 		}
-		outArrangements <- newSolution
-		debugPrint(fmt.Println, "All cells accounted for and arrangement added...")
+		budget.countArrangement()
+		emit(worker, newSolution)
 		return
 	}
 
-	// grab the next cell to fit a domino in - is this efficient?
-	nextCellIdentifier := slices.Collect(maps.Keys(unarrangedCells))[0]
-	nextCell := unarrangedCells[nextCellIdentifier]
-
-	// if at any point we encounter a cell that has no remaining neighbors that aren't accounted for...we have ran into an invalid fitment
-	neighborFound := false
-
+	// otherwise branch on the cell with the fewest ways to pair up
 	for _, neighbor := range []*cell{nextCell.neighborRight, nextCell.neighborBelow, nextCell.neighborLeft, nextCell.neighborAbove} {
 		// is there a neighbor at all?
 		if neighbor == nil {
 			continue
 		}
 		// if the neighbor has been used by another domino, skip it
-		if _, ok := unarrangedCells[neighbor.identifier()]; !ok {
+		if !unarrangedCells.has(neighbor.index) {
 			continue
 		}
 
-		neighborFound = true
-
-		// generate the location to add
-		addedLocation := *(&DominoArrangementLocation{
-			cell1: nextCell.identifier(),
-			cell2: neighbor.identifier(),
-		}).addBlacklistedDominoIDs(game)
-
-		// add the domino location to the list
+		// make sure the location can be given a domino along with the others before adding it to the list
+		addedLocation := game.newLocation(nextCell, neighbor)
+		if !matching.push(addedLocation) {
+			debugPrint(fmt.Printf, "Abandoning tiling - no dominoes left for location %s", addedLocation)
+			budget.countPrunedTiling()
+			continue
+		}
+		budget.countBranchedLocation()
 		locations = append(locations, addedLocation)
 
-		// remove the cell and neighbor from the list
-		delete(unarrangedCells, nextCell.identifier())
-		delete(unarrangedCells, neighbor.identifier())
+		// remove the cell and neighbor from the set - it is passed by value, so backtracking is free
+		nextUnarrangedCells := unarrangedCells
+		nextUnarrangedCells.remove(nextCell.index)
+		nextUnarrangedCells.remove(neighbor.index)
 
-		// perform the next placement recursively
-		findDominoArrangements(game, unarrangedCells, locations, outArrangements)
+		// perform the next placement recursively, or hand it off with its own copy of the state if another worker is idle
+		if worker.shouldSplit(len(locations)) {
+			locationsCopy, matchingCopy := slices.Clone(locations), matching.clone()
+			worker.spawn(func(w *schedulerWorker) {
+				findDominoArrangements(ctx, game, budget, w, nextUnarrangedCells, locationsCopy, matchingCopy, emit)
+			})
+		} else {
+			findDominoArrangements(ctx, game, budget, worker, nextUnarrangedCells, locations, matching, emit)
+		}
 
 		// backtrack
-		unarrangedCells[neighbor.identifier()] = neighbor
-		unarrangedCells[nextCell.identifier()] = nextCell
 		locations = locations[0 : len(locations)-1]
+		matching.pop()
 	}
+}
 
-	if !neighborFound {
-		debugPrint(fmt.Printf, "Attempted arrangement resulted in an orphaned cell - %d cells unarranged...\n", len(unarrangedCells))
-		return
+// number of a cell's neighbors that haven't been arranged yet
+func (c *cell) freeNeighbors(unarrangedCells cellSet) int {
+	n := 0
+	for _, neighbor := range []*cell{c.neighborRight, c.neighborBelow, c.neighborLeft, c.neighborAbove} {
+		if neighbor != nil && unarrangedCells.has(neighbor.index) {
+			n++
+		}
+	}
+	return n
+}
+
+// the first of a cell's neighbors that hasn't been arranged yet, or nil if there are none
+func (c *cell) firstFreeNeighbor(unarrangedCells cellSet) *cell {
+	for _, neighbor := range []*cell{c.neighborRight, c.neighborBelow, c.neighborLeft, c.neighborAbove} {
+		if neighbor != nil && unarrangedCells.has(neighbor.index) {
+			return neighbor
+		}
 	}
+	return nil
 }
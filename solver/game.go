@@ -12,7 +12,13 @@ type Game struct {
 	conditions []*condition
 	dominoes   []*domino
 	// helpers for solving
-	inPlayCellsByIdentifier map[string]*cell
+	cells              []*cell                  // in-play cells in reading order, so cells[c.index] == c
+	inPlayCells        cellSet                  // every in-play cell
+	components         []boardComponent         // connected groups of in-play cells, in reading order
+	dominoKinds        []dominoKind             // distinct kinds of dominoes, in the order they first appear
+	dominoKindIndex    map[dominoKind]int       // position of each kind in dominoKinds
+	locationBlacklists map[[2]int]dominoKindSet // kinds that can't go in each pair of neighboring cells, by cell indices
+	zobrist            *zobristKeys             // for hashing search states
 }
 
 // ParseInputGame - loads a game board from input
@@ -23,7 +29,6 @@ func ParseInputGame(
 		panic("nil input")
 	}
 	game := new(Game)
-	game.inPlayCellsByIdentifier = make(map[string]*cell)
 
 	// cell initialization
 	{
@@ -90,12 +95,24 @@ func ParseInputGame(
 						neighborAbove.neighborBelow = cell
 					}
 				}
-
-				// make the cell easily accessible by its identifier
-				game.inPlayCellsByIdentifier[cell.identifier()] = cell
 			}
 		}
 		game.board = board
+
+		// number the in-play cells in reading order, which is how they are tracked while searching
+		for _, row := range board {
+			for _, c := range row {
+				if !c.inPlay {
+					continue
+				}
+				if len(game.cells) == maxInPlayCells {
+					return nil, fmt.Errorf("input board has more than %d cells in play", maxInPlayCells)
+				}
+				c.index = len(game.cells)
+				game.cells = append(game.cells, c)
+				game.inPlayCells.add(c.index)
+			}
+		}
 	}
 
 	// condition initialization
@@ -110,6 +127,10 @@ func ParseInputGame(
 			if err != nil {
 				return nil, err
 			}
+			if len(condition.cellIdentifiers) == 0 {
+				return nil, errors.New("input condition has no cells")
+			}
+			condition.index = len(conditions)
 			// fact check that all the locations are actually valid cells
 			for _, conditionCellLoc := range condition.cellIdentifiers {
 				xPos, yPos, err := cellIdentifierToBoardPos(conditionCellLoc)
@@ -128,6 +149,8 @@ func ParseInputGame(
 				}
 				// link condition to cell (in case this is needed/useful)
 				cell.applicableConditions = append(cell.applicableConditions, condition)
+				condition.cells = append(condition.cells, cell.index)
+				condition.cellMask.add(cell.index)
 			}
 			conditions = append(conditions, condition)
 		}
@@ -141,20 +164,83 @@ func ParseInputGame(
 		}
 
 		dominoes := make([]*domino, 0)
+		game.dominoKindIndex = make(map[dominoKind]int)
 		for _, inputDomino := range *input.Dominoes {
 			domino, err := parseInputDomino(&inputDomino)
 			if err != nil {
 				return nil, err
 			}
-			// make the domino easily accessible by its identifier
 			dominoes = append(dominoes, domino)
+			// identical dominoes are tracked as one kind with a count
+			if _, ok := game.dominoKindIndex[domino.kind()]; !ok {
+				game.dominoKindIndex[domino.kind()] = len(game.dominoKinds)
+				game.dominoKinds = append(game.dominoKinds, domino.kind())
+			}
 		}
 		game.dominoes = dominoes
 	}
 
+	// board analysis - bail out now if the dominoes can't cover the board, rather than after a fruitless search
+	if err := game.analyzeBoard(); err != nil {
+		return nil, err
+	}
+
+	// domain initialization
+	{
+		dominoValues := valueDomain(0)
+		for _, d := range game.dominoes {
+			dominoValues |= singleValueDomain(d.val1) | singleValueDomain(d.val2)
+		}
+		domains := make(cellDomains, len(game.cells))
+		for i := range domains {
+			domains[i] = dominoValues
+		}
+		if !propagateConditions(game.conditions, newBoardValues(len(game.cells)), domains) {
+			debugPrint(fmt.Println, "Conditions cannot be met by the dominoes before placing any of them...")
+		}
+		for _, c := range game.cells {
+			c.domain = domains[c.index]
+		}
+	}
+
+	// location initialization - every arrangement is made of the same pairs of neighboring cells, so the dominoes
+	// that can't go in each pair are only worked out once
+	{
+		game.locationBlacklists = make(map[[2]int]dominoKindSet)
+		for _, c := range game.cells {
+			for _, neighbor := range []*cell{c.neighborRight, c.neighborBelow} {
+				if neighbor == nil {
+					continue
+				}
+				location := &DominoArrangementLocation{cell1: c, cell2: neighbor}
+				game.locationBlacklists[[2]int{c.index, neighbor.index}] = location.addBlacklistedDominoKinds(game).blacklistedDominoKinds
+			}
+		}
+	}
+	game.zobrist = newZobristKeys(game)
+
 	return game, nil
 }
 
+// creates a fresh set of cell domains for a search, starting from each cell's initial domain
+func (g *Game) initialCellDomains() cellDomains {
+	domains := make(cellDomains, len(g.cells))
+	for _, c := range g.cells {
+		domains[c.index] = c.domain
+	}
+	return domains
+}
+
+// creates the location covering two neighboring cells, with its blacklist filled in
+func (g *Game) newLocation(cell1, cell2 *cell) DominoArrangementLocation {
+	key := [2]int{min(cell1.index, cell2.index), max(cell1.index, cell2.index)}
+	return DominoArrangementLocation{
+		cell1:                  cell1,
+		cell2:                  cell2,
+		blacklistedDominoKinds: g.locationBlacklists[key],
+	}
+}
+
 // I hate it but this is my confirmation that input parsing worked for now
 func (b Game) Print() {
 	// pretty print the board
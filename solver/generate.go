@@ -0,0 +1,459 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"djlovell/nyt_pips_solver/input"
+)
+
+// Puzzle generation works backwards from a solution:
+//   - a board shape is grown one domino at a time, so it comes with a tiling
+//   - dominoes are drawn from a double-six set and laid on the tiling, which gives every cell its value
+//   - the board is carved into regions, and each gets a condition its values meet - either exactly (a sum, "=", or
+//     "!=") or loosely ("<N" or ">N")
+//   - while the puzzle has more than one solution, the conditions around the cells where two of the solutions differ
+//     are tightened, until the solver proves there is only one
+//
+// Shapes that can't be made unique, or don't come out at the target difficulty, are thrown away for a new one. So are
+// shapes where a uniqueness check runs out of search budget before it can tell - without a limit, a loose early draft
+// can take a very long time to check.
+//
+// Every check runs on a single worker, so the same two solutions are found each time, the same cells get tightened,
+// and the same seed always ends up with the same puzzle.
+
+// GenerateOptions - settings for GeneratePuzzle
+type GenerateOptions struct {
+	Seed          uint64 // the same seed and options always generate the same puzzle
+	Width, Height int    // size of the grid the board is grown in
+	// number of regions to start with (0 for about one per three cells) - more can be added while tightening
+	Regions    int
+	Difficulty DifficultyBucket // rating to aim for
+	Attempts   int              // boards to try before settling for one that missed the difficulty (0 for 50)
+	// how uniqueness is checked, including what makes solutions different - the node limit applies to each check
+	// (0 for defaultGenerateCheckNodes), and the workers are always cut down to one
+	Search SearchOptions
+}
+
+// GeneratedPuzzle - a generated puzzle, ready to be written out as an input file
+type GeneratedPuzzle struct {
+	Game     *input.Game
+	Rating   Rating
+	Attempts int // boards tried
+	// boards thrown away because a uniqueness check or the rating ran out of search budget
+	OutOfBudget int
+}
+
+// BudgetExceeded - whether any board was thrown away for running out of search budget, so a board that would have
+// made the target difficulty might have been missed
+func (p GeneratedPuzzle) BudgetExceeded() bool {
+	return p.OutOfBudget > 0
+}
+
+// ErrNoPuzzleGenerated - no board tried could be made to have a unique solution
+var ErrNoPuzzleGenerated = errors.New("no puzzle with a unique solution could be generated")
+
+// the most dominoes a double-six set has
+const doubleSixDominoes = 28
+
+// search nodes each uniqueness check gets unless the options say otherwise - plenty for the pipeline engine on the
+// default board size
+const defaultGenerateCheckNodes = 1_000_000
+
+// GeneratePuzzle - generates a puzzle with a unique solution, aiming for the difficulty in the options. If no
+// board comes out at that difficulty within the attempts allowed, the closest one is returned.
+func GeneratePuzzle(ctx context.Context, opts GenerateOptions) (*GeneratedPuzzle, error) {
+	if opts.Width < 1 || opts.Height < 1 || opts.Width*opts.Height < 2 {
+		return nil, errors.New("board must have room for at least one domino")
+	}
+	if opts.Regions < 0 {
+		return nil, errors.New("number of regions cannot be negative")
+	}
+	attempts := opts.Attempts
+	if attempts <= 0 {
+		attempts = 50
+	}
+	if opts.Search.MaxNodes == 0 {
+		opts.Search.MaxNodes = defaultGenerateCheckNodes
+	}
+	opts.Search.Workers = 1
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Generating a puzzle...")
+
+	r := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	var best *GeneratedPuzzle
+	outOfBudget, tried := 0, 0
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if ctx.Err() != nil {
+			break
+		}
+		tried = attempt
+		draft := newPuzzleDraft(r, opts)
+		switch draft.makeUnique(ctx, r, opts.Search) {
+		case draftUnique:
+		case draftStuck:
+			debugPrint(fmt.Printf, "Attempt %d couldn't be made unique - starting over...\n", attempt)
+			continue
+		case draftOutOfBudget:
+			debugPrint(fmt.Printf, "Attempt %d ran out of search budget checking uniqueness - starting over...\n", attempt)
+			outOfBudget++
+			continue
+		default:
+			panic("unhandled draft outcome")
+		}
+		inputGame := draft.inputGame(r)
+		game, err := ParseInputGame(inputGame)
+		if err != nil {
+			panic("generated puzzle failed to parse - " + err.Error())
+		}
+		puzzle := &GeneratedPuzzle{
+			Game:     inputGame,
+			Rating:   RatePuzzle(ctx, game, opts.Search),
+			Attempts: attempt,
+		}
+		if puzzle.Rating.Partial() {
+			debugPrint(fmt.Printf, "Attempt %d ran out of search budget while being rated - starting over...\n", attempt)
+			outOfBudget++
+			continue
+		}
+		debugPrint(fmt.Printf, "Attempt %d is unique and rated %.1f (%s)\n", attempt, puzzle.Rating.Score, puzzle.Rating.Bucket)
+		if puzzle.Rating.Bucket == opts.Difficulty {
+			puzzle.OutOfBudget = outOfBudget
+			return puzzle, nil
+		}
+		if best == nil || bucketDistance(puzzle.Rating.Bucket, opts.Difficulty) < bucketDistance(best.Rating.Bucket, opts.Difficulty) {
+			best = puzzle
+		}
+	}
+	if best == nil {
+		if outOfBudget > 0 {
+			return nil, fmt.Errorf(
+				"%w - %d of the %d boards tried ran out of search budget checking uniqueness (%w), so a higher node limit or timeout may help",
+				ErrNoPuzzleGenerated, outOfBudget, tried, ErrSearchBudgetExceeded,
+			)
+		}
+		return nil, ErrNoPuzzleGenerated
+	}
+	best.Attempts, best.OutOfBudget = tried, outOfBudget
+	return best, nil
+}
+
+// how many buckets apart two difficulties are
+func bucketDistance(l, r DifficultyBucket) int {
+	return int(max(l-r, r-l))
+}
+
+// a position on the board being generated
+type draftPos struct {
+	x, y int
+}
+
+// a region of the board being generated and its condition
+type draftRegion struct {
+	cells      []draftPos
+	expression string // as in input files - "N", "<N", ">N", "=", or "!="
+	operand    int
+}
+
+// puzzleDraft - a puzzle being generated, along with the solution it was built from
+type puzzleDraft struct {
+	width, height int
+	values        map[draftPos]int // the solution's value in every in-play cell
+	dominoes      []dominoKind
+	regions       []*draftRegion
+	regionOf      map[draftPos]*draftRegion
+	// how likely a region is to get a loose condition, higher for harder puzzles
+	looseness float64
+}
+
+// grows a random board, tiles it with random dominoes, and carves it into regions
+func newPuzzleDraft(r *rand.Rand, opts GenerateOptions) *puzzleDraft {
+	d := &puzzleDraft{
+		width:    opts.Width,
+		height:   opts.Height,
+		values:   make(map[draftPos]int),
+		regionOf: make(map[draftPos]*draftRegion),
+	}
+	switch opts.Difficulty {
+	case DifficultyEasy:
+		d.looseness = 0.1
+	case DifficultyMedium:
+		d.looseness = 0.35
+	case DifficultyHard:
+		d.looseness = 0.6
+	default:
+		panic("unhandled difficulty bucket")
+	}
+
+	// draw the dominoes - about two thirds of the grid gets covered
+	numDominoes := min(doubleSixDominoes, max(1, opts.Width*opts.Height/3))
+	set := make([]dominoKind, 0, doubleSixDominoes)
+	for low := minPipValue; low <= maxPipValue; low++ {
+		for high := low; high <= maxPipValue; high++ {
+			set = append(set, dominoKind{low: low, high: high})
+		}
+	}
+	r.Shuffle(len(set), func(i, j int) {
+		set[i], set[j] = set[j], set[i]
+	})
+
+	// grow the board a domino at a time next to what is already there, laying the drawn dominoes as it goes
+	inBounds := func(p draftPos) bool {
+		return p.x >= 0 && p.x < d.width && p.y >= 0 && p.y < d.height
+	}
+	free := func(p draftPos) bool {
+		_, taken := d.values[p]
+		return inBounds(p) && !taken
+	}
+	lay := func(p1, p2 draftPos) {
+		kind := set[len(d.dominoes)]
+		d.dominoes = append(d.dominoes, kind)
+		o := kind.orientations()[r.IntN(len(kind.orientations()))]
+		d.values[p1], d.values[p2] = o[0], o[1]
+	}
+	start := draftPos{r.IntN(d.width), r.IntN(d.height)}
+	if starts := freeNeighbors(start, free); len(starts) > 0 {
+		lay(start, starts[r.IntN(len(starts))])
+	}
+	for len(d.dominoes) > 0 && len(d.dominoes) < numDominoes {
+		candidates := make([][2]draftPos, 0)
+		for _, p := range d.positions() {
+			for _, p1 := range freeNeighbors(p, free) {
+				for _, p2 := range freeNeighbors(p1, free) {
+					candidates = append(candidates, [2]draftPos{p1, p2})
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		c := candidates[r.IntN(len(candidates))]
+		lay(c[0], c[1])
+	}
+
+	// carve regions from random starting cells, each growing to a random size
+	positions := d.positions()
+	r.Shuffle(len(positions), func(i, j int) {
+		positions[i], positions[j] = positions[j], positions[i]
+	})
+	numRegions := opts.Regions
+	if numRegions == 0 {
+		numRegions = max(1, len(positions)/3)
+	}
+	unassigned := func(p draftPos) bool {
+		_, inPlay := d.values[p]
+		_, assigned := d.regionOf[p]
+		return inPlay && !assigned
+	}
+	for _, seed := range positions[:min(numRegions, len(positions))] {
+		if !unassigned(seed) {
+			continue
+		}
+		region := &draftRegion{cells: []draftPos{seed}}
+		d.regionOf[seed] = region
+		size := []int{1, 2, 2, 2, 3, 3, 4}[r.IntN(7)]
+		for len(region.cells) < size {
+			growth := make([]draftPos, 0)
+			for _, p := range region.cells {
+				growth = append(growth, freeNeighbors(p, unassigned)...)
+			}
+			if len(growth) == 0 {
+				break
+			}
+			p := growth[r.IntN(len(growth))]
+			region.cells = append(region.cells, p)
+			d.regionOf[p] = region
+		}
+		d.setCondition(r, region, r.Float64() < d.looseness)
+		d.regions = append(d.regions, region)
+	}
+	return d
+}
+
+// the in-play positions in reading order
+func (d *puzzleDraft) positions() []draftPos {
+	positions := make([]draftPos, 0, len(d.values))
+	for y := range d.height {
+		for x := range d.width {
+			if _, ok := d.values[draftPos{x, y}]; ok {
+				positions = append(positions, draftPos{x, y})
+			}
+		}
+	}
+	return positions
+}
+
+// the neighbors of a position that pass the filter, in a fixed order
+func freeNeighbors(p draftPos, filter func(draftPos) bool) []draftPos {
+	neighbors := make([]draftPos, 0, 4)
+	for _, n := range []draftPos{{p.x + 1, p.y}, {p.x, p.y + 1}, {p.x - 1, p.y}, {p.x, p.y - 1}} {
+		if filter(n) {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// gives a region a condition its solution values meet - loosely ("<N" or ">N") or exactly
+func (d *puzzleDraft) setCondition(r *rand.Rand, region *draftRegion, loose bool) {
+	sum, used, allSame := 0, valueDomain(0), true
+	for _, p := range region.cells {
+		v := d.values[p]
+		sum += v
+		allSame = allSame && (used == 0 || used.has(v))
+		used |= singleValueDomain(v)
+	}
+	allDistinct := used.size() == len(region.cells)
+
+	switch {
+	case loose && r.IntN(2) == 0:
+		region.expression, region.operand = "<N", sum+1+r.IntN(3)
+	case loose && sum > 0:
+		region.expression, region.operand = ">N", max(0, sum-1-r.IntN(3))
+	case len(region.cells) > 1 && allSame && r.IntN(10) < 7:
+		region.expression, region.operand = "=", 0
+	case len(region.cells) > 2 && allDistinct && r.IntN(10) < 3:
+		region.expression, region.operand = "!=", 0
+	default:
+		region.expression, region.operand = "N", sum
+	}
+}
+
+// draftOutcome - how making a draft unique went
+type draftOutcome int
+
+const (
+	draftUnique      draftOutcome = iota
+	draftStuck                    // no more conditions could be tightened, or it took too many tightenings
+	draftOutOfBudget              // a uniqueness check ran out of search budget before it could tell
+)
+
+// tightens conditions until the puzzle has only one solution
+func (d *puzzleDraft) makeUnique(ctx context.Context, r *rand.Rand, opts SearchOptions) draftOutcome {
+	for range 2 * len(d.values) {
+		game, err := ParseInputGame(d.inputGame(r))
+		if err != nil {
+			panic("generated puzzle failed to parse - " + err.Error())
+		}
+		result := CheckUniqueness(ctx, game, opts)
+		switch result.Verdict {
+		case UniquenessVerdictUnique:
+			return draftUnique
+		case UniquenessVerdictAmbiguous:
+			if !d.tighten(r, result.DifferingCells) {
+				return draftStuck
+			}
+		case UniquenessVerdictUnsolvable:
+			panic("generated puzzle has no solution, even though it was built from one")
+		case UniquenessVerdictUnknown:
+			return draftOutOfBudget
+		default:
+			panic("unhandled uniqueness verdict")
+		}
+	}
+	return draftStuck
+}
+
+// tightens the conditions around one of the cells where two solutions differ - a loose condition on the cell
+// becomes exact, and otherwise a cell there (or next to it) without a condition gets one, or failing that the cell is
+// split off from its region to get one of its own
+func (d *puzzleDraft) tighten(r *rand.Rand, differingCells []string) bool {
+	differing := make([]draftPos, 0, len(differingCells))
+	for _, identifier := range differingCells {
+		x, y, err := cellIdentifierToBoardPos(identifier)
+		if err != nil {
+			panic("bad differing cell identifier")
+		}
+		differing = append(differing, draftPos{x, y})
+	}
+	r.Shuffle(len(differing), func(i, j int) {
+		differing[i], differing[j] = differing[j], differing[i]
+	})
+
+	unconstrained := func(p draftPos) bool {
+		_, inPlay := d.values[p]
+		_, constrained := d.regionOf[p]
+		return inPlay && !constrained
+	}
+	for _, p := range differing {
+		if region, ok := d.regionOf[p]; ok && (region.expression == "<N" || region.expression == ">N") {
+			d.setCondition(r, region, false)
+			return true
+		}
+		candidates := freeNeighbors(p, unconstrained)
+		if unconstrained(p) {
+			candidates = append([]draftPos{p}, candidates...)
+		}
+		if len(candidates) == 0 {
+			// as a last resort, split the cell off from its region so its value is given away outright
+			if region := d.regionOf[p]; len(region.cells) > 1 {
+				region.cells = slices.DeleteFunc(region.cells, func(c draftPos) bool { return c == p })
+				d.setCondition(r, region, false)
+				delete(d.regionOf, p)
+				candidates = []draftPos{p}
+			} else {
+				continue
+			}
+		}
+		region := &draftRegion{cells: []draftPos{candidates[0]}}
+		if others := freeNeighbors(candidates[0], unconstrained); len(others) > 0 && r.IntN(2) == 0 {
+			region.cells = append(region.cells, others[r.IntN(len(others))])
+		}
+		for _, c := range region.cells {
+			d.regionOf[c] = region
+		}
+		d.setCondition(r, region, false)
+		d.regions = append(d.regions, region)
+		return true
+	}
+	return false
+}
+
+// the draft as an input file, with the dominoes in a random order and orientation
+func (d *puzzleDraft) inputGame(r *rand.Rand) *input.Game {
+	cells := make([][]string, d.height)
+	for y := range d.height {
+		cells[y] = make([]string, d.width)
+		for x := range d.width {
+			cells[y][x] = "X"
+			if _, ok := d.values[draftPos{x, y}]; ok {
+				cells[y][x] = "O"
+			}
+		}
+	}
+
+	conditions := make([]input.Condition, 0, len(d.regions))
+	for _, region := range d.regions {
+		positions := slices.Clone(region.cells)
+		slices.SortFunc(positions, func(l, r draftPos) int {
+			if l.y != r.y {
+				return l.y - r.y
+			}
+			return l.x - r.x
+		})
+		conditions = append(conditions, input.NewCondition(region.expression, region.operand, positions2xy(positions)))
+	}
+
+	dominoes := make([]input.Domino, 0, len(d.dominoes))
+	for _, i := range r.Perm(len(d.dominoes)) {
+		kind := d.dominoes[i]
+		o := kind.orientations()[r.IntN(len(kind.orientations()))]
+		val1, val2 := o[0], o[1]
+		dominoes = append(dominoes, input.Domino{Val1: &val1, Val2: &val2})
+	}
+
+	return &input.Game{Cells: &cells, Conditions: &conditions, Dominoes: &dominoes}
+}
+
+func positions2xy(positions []draftPos) [][2]int {
+	xy := make([][2]int, 0, len(positions))
+	for _, p := range positions {
+		xy = append(xy, [2]int{p.x, p.y})
+	}
+	return xy
+}
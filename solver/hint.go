@@ -0,0 +1,191 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"djlovell/nyt_pips_solver/input"
+)
+
+// PartialBoard - dominoes a player has already placed on a game's board
+type PartialBoard struct {
+	placements []deductionPlacement
+}
+
+// ParsePartialBoard - loads the dominoes already placed on a game's board from input, making sure they fit the board
+// and the game's dominoes. The board doesn't have to be correct, just possible to lay out.
+func ParsePartialBoard(game *Game, input *input.Board) (*PartialBoard, error) {
+	if game == nil {
+		panic("nil game")
+	}
+	if input == nil {
+		panic("nil input board")
+	}
+	if input.Placements == nil {
+		return nil, errors.New(`input board file missing "placements"`)
+	}
+
+	board := new(PartialBoard)
+	unplaced := game.newDominoInventory()
+	var covered cellSet
+	for _, inputPlacement := range *input.Placements {
+		if len(inputPlacement.Cells) != 2 || len(inputPlacement.Values) != 2 {
+			return nil, errors.New("input board placement must have two cells and two values")
+		}
+		cells := [2]*cell{}
+		for i, inputCell := range inputPlacement.Cells {
+			if inputCell.X == nil || inputCell.Y == nil {
+				return nil, errors.New(`input board placement cell missing "x" or "y" position`)
+			}
+			c, err := game.cellAt(*inputCell.X, *inputCell.Y)
+			if err != nil {
+				return nil, err
+			}
+			if covered.has(c.index) {
+				return nil, fmt.Errorf("input board covers cell %s with more than one domino", c)
+			}
+			covered.add(c.index)
+			cells[i] = c
+		}
+		if !slices.Contains(cells[0].neighbors(), cells[1]) {
+			return nil, fmt.Errorf("input board places a domino on cells %s, which aren't neighbors", describePair(cells[0], cells[1]))
+		}
+
+		d := domino{val1: inputPlacement.Values[0], val2: inputPlacement.Values[1]}
+		kindIdx, ok := game.dominoKindIndex[d.kind()]
+		if !ok {
+			return nil, fmt.Errorf("input board places domino %s, which isn't one of the game's dominoes", d)
+		}
+		if unplaced[kindIdx] == 0 {
+			return nil, fmt.Errorf("input board places more %s dominoes than the game has", d.kind())
+		}
+		unplaced[kindIdx]--
+		board.placements = append(board.placements, deductionPlacement{
+			cell1:   cells[0],
+			cell2:   cells[1],
+			kindIdx: kindIdx,
+			values:  [2]int{d.val1, d.val2},
+		})
+	}
+	return board, nil
+}
+
+// HintResult - a nudge towards solving a game from a partial board
+type HintResult struct {
+	// the easiest fact that can be deduced next, nil if there is nothing to deduce
+	Step *DeductionStep
+	// whether the board is already complete and correct
+	Solved bool
+	// why the board so far can't lead to any solution (empty otherwise)
+	Inconsistent string
+	// why the check that the board can still be finished ended - if it was cut short before finding a way, the board
+	// might be wrong without the hint saying so
+	StopReason StopReason
+}
+
+// BudgetExceeded - whether the check that the board can still be finished ran out of time or nodes
+func (r HintResult) BudgetExceeded() bool {
+	return r.StopReason == StopReasonTimeout || r.StopReason == StopReasonNodeLimit
+}
+
+// Hint - finds the single easiest fact that can be deduced from the dominoes placed so far (board can be nil for an
+// empty board), with the reason for it. Nothing else is revealed - if no technique applies, the hint is just that
+// the next step takes trial and error.
+//
+// The board is first checked against every solution by counting the ways of finishing it (with the options given),
+// so a mistake shows up as soon as it is made rather than once the player is stuck.
+func Hint(ctx context.Context, game *Game, board *PartialBoard, opts CountOptions) HintResult {
+	if game == nil {
+		panic("nil game")
+	}
+	if board == nil {
+		board = new(PartialBoard)
+	}
+
+	// lay the board out for checking and for deducing
+	state := newDeductionState(game)
+	cellValues := newBoardValues(len(game.cells))
+	conditions := newConditionTracker(game)
+	unplaced := game.newDominoInventory()
+	for _, p := range board.placements {
+		state.place(p)
+		cellValues.set(p.cell1.index, p.values[0])
+		cellValues.set(p.cell2.index, p.values[1])
+		conditions.fill(p.cell1, p.values[0])
+		conditions.fill(p.cell2, p.values[1])
+		unplaced[p.kindIdx]--
+	}
+	for _, cond := range game.conditions {
+		if !conditions.conditionHolds(cond) {
+			return HintResult{Inconsistent: fmt.Sprintf(`the dominoes placed so far break "%s"`, cond)}
+		}
+	}
+
+	// can the board still be finished at all?
+	if opts.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, opts.Timeout)
+		defer cancelTimeout()
+	}
+	budget := NewSearchBudget(opts.MaxNodes)
+	// one way of finishing it is enough to know
+	completions := countFrom(ctx, game, budget, newTranspositionTable(opts.TranspositionMemory), cellValues, conditions, unplaced, 1)
+	result := HintResult{}
+	switch {
+	case completions > 0:
+	case budget.Exceeded():
+		result.StopReason = StopReasonNodeLimit
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.StopReason = StopReasonTimeout
+	case ctx.Err() != nil:
+		result.StopReason = StopReasonCancelled
+	}
+	debugPrint(fmt.Printf, "Board can be finished: %t\n", completions > 0)
+	if result.BudgetExceeded() {
+		debugPrint(fmt.Printf, "The check %s, so the board might not lead anywhere...\n", result.StopReason)
+	}
+	if completions == 0 && result.StopReason == StopReasonExhausted {
+		result.Inconsistent = "no solution can be reached from the dominoes placed so far - at least one of them is wrong"
+		return result
+	}
+	if state.solved() {
+		result.Solved = true
+		return result
+	}
+
+	step, contradiction := state.nextStep()
+	switch {
+	case contradiction != "":
+		result.Inconsistent = contradiction
+		return result
+	case step == nil:
+		remaining := make([]*cell, 0)
+		for _, c := range game.cells {
+			if !cellValues.filled.has(c.index) {
+				remaining = append(remaining, c)
+			}
+		}
+		step = &DeductionStep{
+			Technique:  TechniqueSearch,
+			Cells:      cellIdentifiers(remaining),
+			Conclusion: "the next step takes trial and error",
+			Reason:     fmt.Sprintf("No technique applies to the %d cells left, so try a domino and see if it leads anywhere.", len(remaining)),
+		}
+	}
+	result.Step = step
+	return result
+}
+
+// the in-play cell at a board position
+func (g *Game) cellAt(xPos, yPos int) (*cell, error) {
+	if yPos < 0 || yPos >= len(g.board) || xPos < 0 || xPos >= len(g.board[yPos]) {
+		return nil, fmt.Errorf("cell %s is not on the board", boardPosToCellIdentifier(xPos, yPos))
+	}
+	c := g.board[yPos][xPos]
+	if !c.inPlay {
+		return nil, fmt.Errorf("cell %s is not in play", c)
+	}
+	return c, nil
+}
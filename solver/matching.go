@@ -0,0 +1,165 @@
+package solver
+
+import "slices"
+
+// dominoMatching - a matching of arrangement locations to the dominoes that will go in them, kept up to date as
+// locations are added and removed while tiling, and as dominoes are placed while filling an arrangement. If a
+// location can't be matched to a domino (even by shuffling the dominoes matched to the other locations around), then
+// no way of finishing the tiling or filling can work with the dominoes, so it isn't worth finishing.
+//
+// Identical dominoes are one kind with a count, so each kind can be matched to as many locations as there are
+// dominoes of that kind.
+type dominoMatching struct {
+	kinds       []dominoKind                // Game.dominoKinds
+	capacity    dominoInventory             // dominoes of each kind
+	used        dominoInventory             // locations matched to each kind
+	locations   []DominoArrangementLocation // in the order they were added
+	matchedKind []int                       // kind matched to each location
+	// values the cells can still hold while filling an arrangement - nil while tiling, where only the blacklists
+	// say which dominoes can go where
+	domains cellDomains
+}
+
+// creates an empty matching for a game's dominoes
+func newDominoMatching(g *Game) *dominoMatching {
+	return &dominoMatching{
+		kinds:    g.dominoKinds,
+		capacity: g.newDominoInventory(),
+		used:     make(dominoInventory, len(g.dominoKinds)),
+	}
+}
+
+// creates a matching of every location in an arrangement to the game's dominoes, given the values the cells can
+// hold. Returns false if the arrangement can't be filled with the dominoes at all.
+func newFillMatching(g *Game, locations []DominoArrangementLocation, domains cellDomains) (*dominoMatching, bool) {
+	m := newDominoMatching(g)
+	m.domains = domains
+	for _, l := range locations {
+		if !m.push(l) {
+			return m, false
+		}
+	}
+	return m, true
+}
+
+// push - adds a location and finds a domino for it, rearranging the rest of the matching if needed. Returns false,
+// leaving the matching as it was, if there is no way to give every location a domino that can go there.
+func (m *dominoMatching) push(l DominoArrangementLocation) bool {
+	m.locations = append(m.locations, l)
+	m.matchedKind = append(m.matchedKind, -1)
+	if m.augment(len(m.locations)-1, make([]bool, len(m.capacity))) {
+		return true
+	}
+	// a failed search for an augmenting path never changes the matching, so only the location has to go
+	m.locations = m.locations[:len(m.locations)-1]
+	m.matchedKind = m.matchedKind[:len(m.matchedKind)-1]
+	return false
+}
+
+// pop - removes the most recently pushed location - the rest of the matching stays valid without it
+func (m *dominoMatching) pop() {
+	last := len(m.locations) - 1
+	m.used[m.matchedKind[last]]--
+	m.locations = m.locations[:last]
+	m.matchedKind = m.matchedKind[:last]
+}
+
+// place - the matching left once a domino of the kind is placed in the location and the domains have been narrowed
+// around it, along with false if the rest of the locations can no longer all be given a domino.
+//
+// The matching is repaired rather than rebuilt - only the locations that lost their domino (to the placement, or to
+// the narrowed domains) have to find a new one. The receiver is left untouched, so backtracking is just going back
+// to it.
+func (m *dominoMatching) place(l DominoArrangementLocation, kindIdx int, domains cellDomains) (*dominoMatching, bool) {
+	next := m.clone()
+	next.domains = domains
+
+	// the location is filled, so it no longer needs a domino from the matching
+	i := slices.IndexFunc(next.locations, func(o DominoArrangementLocation) bool {
+		return o.cell1 == l.cell1 && o.cell2 == l.cell2
+	})
+	if i == -1 {
+		panic("placed location is not in the matching")
+	}
+	next.used[next.matchedKind[i]]--
+	next.locations = slices.Delete(next.locations, i, i+1)
+	next.matchedKind = slices.Delete(next.matchedKind, i, i+1)
+
+	// one fewer domino of the kind to go around - if all of them were matched, one location has to give its domino up
+	next.capacity[kindIdx]--
+	if next.used[kindIdx] > next.capacity[kindIdx] {
+		other := slices.Index(next.matchedKind, kindIdx)
+		next.matchedKind[other] = -1
+		next.used[kindIdx]--
+	}
+
+	// locations whose matched domino no longer fits the values their cells can hold give it up as well
+	for i, k := range next.matchedKind {
+		if k != -1 && !next.fits(next.locations[i], k) {
+			next.matchedKind[i] = -1
+			next.used[k]--
+		}
+	}
+
+	// then every location without a domino looks for one
+	for i, k := range next.matchedKind {
+		if k == -1 && !next.augment(i, make([]bool, len(next.capacity))) {
+			return next, false
+		}
+	}
+	return next, true
+}
+
+// whether a domino of the kind could go in the location - it can't be blacklisted there, and while filling, one of
+// its orientations has to fit the values the cells can still hold
+func (m *dominoMatching) fits(l DominoArrangementLocation, kindIdx int) bool {
+	if l.blacklistedDominoKinds.has(kindIdx) {
+		return false
+	}
+	if m.domains == nil {
+		return true
+	}
+	for _, o := range m.kinds[kindIdx].orientations() {
+		if m.domains[l.cell1.index].has(o[0]) && m.domains[l.cell2.index].has(o[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// looks for an augmenting path from an unmatched location (Kuhn's algorithm) - either a kind with a domino to spare,
+// or a kind whose dominoes are all matched but one of its locations can move to another kind
+func (m *dominoMatching) augment(location int, visitedKinds []bool) bool {
+	for kindIdx := range m.capacity {
+		if visitedKinds[kindIdx] || m.capacity[kindIdx] == 0 || !m.fits(m.locations[location], kindIdx) {
+			continue
+		}
+		visitedKinds[kindIdx] = true
+
+		if m.used[kindIdx] < m.capacity[kindIdx] {
+			m.matchedKind[location] = kindIdx
+			m.used[kindIdx]++
+			return true
+		}
+		for other, otherKind := range m.matchedKind {
+			if other != location && otherKind == kindIdx && m.augment(other, visitedKinds) {
+				// the other location moved to another kind, leaving this one's domino to spare
+				m.matchedKind[location] = kindIdx
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copy for handing off to another worker
+func (m *dominoMatching) clone() *dominoMatching {
+	return &dominoMatching{
+		kinds:       m.kinds,
+		capacity:    slices.Clone(m.capacity),
+		used:        slices.Clone(m.used),
+		locations:   slices.Clone(m.locations),
+		matchedKind: slices.Clone(m.matchedKind),
+		domains:     m.domains,
+	}
+}
@@ -0,0 +1,307 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"djlovell/nyt_pips_solver/input"
+)
+
+// Minimizing works on the input file's conditions, greedily and in the order they are listed:
+//   - first, each condition is removed if the puzzle stays unique without it
+//   - then, each sum left is loosened - an exact sum into "<N" or ">N", and those as far as they will go - while the
+//     puzzle stays unique
+//
+// Taking away or loosening a condition only ever lets more solutions in, so a condition that had to stay while the
+// others were all there has to stay once some of them are gone too. That makes every condition left necessary in the
+// minimized puzzle, which is checked once more at the end to say why.
+
+// ConditionOutcome - what minimizing did to a condition
+type ConditionOutcome int
+
+const (
+	// ConditionRemoved - the puzzle is unique without the condition
+	ConditionRemoved ConditionOutcome = iota
+	// ConditionLoosened - the condition's sum was loosened as far as it could be
+	ConditionLoosened
+	// ConditionKept - the condition was needed as it was
+	ConditionKept
+)
+
+func (o ConditionOutcome) String() string {
+	switch o {
+	case ConditionRemoved:
+		return "removed"
+	case ConditionLoosened:
+		return "loosened"
+	case ConditionKept:
+		return "kept"
+	default:
+		panic("unhandled condition outcome")
+	}
+}
+
+// ConditionReport - what happened to one of the input file's conditions, and why
+type ConditionReport struct {
+	Index    int    // position in the input file's conditions
+	Original string // the condition as it was
+	Outcome  ConditionOutcome
+	Now      string // the condition as it is now, empty if removed
+	Reason   string
+}
+
+func (r ConditionReport) String() string {
+	switch r.Outcome {
+	case ConditionRemoved:
+		return fmt.Sprintf("#%d %s - %s: %s", r.Index, r.Original, r.Outcome, r.Reason)
+	case ConditionLoosened:
+		return fmt.Sprintf("#%d %s - %s to \"%s\": %s", r.Index, r.Original, r.Outcome, r.Now, r.Reason)
+	default:
+		return fmt.Sprintf("#%d %s - %s: %s", r.Index, r.Original, r.Outcome, r.Reason)
+	}
+}
+
+// MinimizeResult - a puzzle minimized by MinimizePuzzle
+type MinimizeResult struct {
+	Game       *input.Game       // the minimized puzzle, ready to be written out as an input file
+	Conditions []ConditionReport // one for every condition in the original input file, in the same order
+	Checks     int               // uniqueness checks run
+	// why the first check to be cut short ended, if any were - conditions whose checks were cut short are kept, so the
+	// puzzle might not be as lean as it could be
+	StopReason StopReason
+}
+
+// BudgetExceeded - whether any check ran out of time or nodes, keeping conditions that might not be needed
+func (r MinimizeResult) BudgetExceeded() bool {
+	return r.StopReason == StopReasonTimeout || r.StopReason == StopReasonNodeLimit
+}
+
+// Removed - how many conditions were removed
+func (r MinimizeResult) Removed() int {
+	removed := 0
+	for _, c := range r.Conditions {
+		if c.Outcome == ConditionRemoved {
+			removed++
+		}
+	}
+	return removed
+}
+
+// ErrNotUnique - the puzzle to minimize doesn't have exactly one solution to begin with
+var ErrNotUnique = errors.New("puzzle must have exactly one solution to be minimized")
+
+// MinimizePuzzle - removes conditions from a uniquely solvable puzzle, and loosens the sums left, for as long as the
+// solution stays unique (by the identity in the options). A condition is only taken away once the solver has proven
+// the puzzle unique without it, so a search cut short by the options' budget keeps it.
+func MinimizePuzzle(ctx context.Context, inputGame *input.Game, opts SearchOptions) (*MinimizeResult, error) {
+	if inputGame == nil {
+		panic("nil input game")
+	}
+	if inputGame.Conditions == nil {
+		return nil, errors.New(`input file missing "conditions"`)
+	}
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Minimizing puzzle...")
+
+	m := &minimizer{
+		input:      inputGame,
+		opts:       opts,
+		conditions: slices.Clone(*inputGame.Conditions),
+		kept:       make([]bool, len(*inputGame.Conditions)),
+		result:     &MinimizeResult{},
+	}
+	for i := range m.kept {
+		m.kept[i] = true
+	}
+
+	check, err := m.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if check.Verdict == UniquenessVerdictUnknown {
+		return nil, fmt.Errorf("%w before the puzzle could be proven unique", ErrSearchBudgetExceeded)
+	}
+	if check.Verdict != UniquenessVerdictUnique {
+		return nil, fmt.Errorf("%w - it is %s", ErrNotUnique, check.Verdict.String())
+	}
+
+	// remove what isn't needed
+	for i := range m.conditions {
+		m.kept[i] = false
+		if check, err := m.check(ctx); err != nil {
+			return nil, err
+		} else if check.Verdict != UniquenessVerdictUnique {
+			m.kept[i] = true
+		} else {
+			debugPrint(fmt.Printf, "Condition #%d isn't needed - removing it...\n", i)
+		}
+	}
+
+	// loosen the sums left, following the first way of loosening each one that keeps the puzzle unique
+	loosened := make([]bool, len(m.conditions))
+	for i := range m.conditions {
+		if !m.kept[i] {
+			continue
+		}
+		for _, chain := range looserConditions(m.conditions[i]) {
+			for _, looser := range chain {
+				original := m.conditions[i]
+				m.conditions[i] = looser
+				if check, err := m.check(ctx); err != nil {
+					return nil, err
+				} else if check.Verdict != UniquenessVerdictUnique {
+					m.conditions[i] = original
+					break
+				}
+				debugPrint(fmt.Printf, "Condition #%d loosened to %s...\n", i, describeInputCondition(looser))
+				loosened[i] = true
+			}
+			if loosened[i] {
+				break
+			}
+		}
+	}
+
+	// say why each condition left is needed - the puzzle without it (or loosened any further) has another solution
+	for i, original := range *inputGame.Conditions {
+		report := ConditionReport{Index: i, Original: describeInputCondition(original), Outcome: ConditionKept}
+		if !m.kept[i] {
+			report.Outcome = ConditionRemoved
+			report.Reason = "the conditions left already rule out every other solution"
+			m.result.Conditions = append(m.result.Conditions, report)
+			continue
+		}
+		report.Now = describeInputCondition(m.conditions[i])
+		if loosened[i] {
+			report.Outcome = ConditionLoosened
+		}
+
+		m.kept[i] = false
+		check, err := m.check(ctx)
+		m.kept[i] = true
+		if err != nil {
+			return nil, err
+		}
+		report.Reason = m.necessity(check, "without it")
+		for _, chain := range looserConditions(m.conditions[i]) {
+			current := m.conditions[i]
+			m.conditions[i] = chain[0]
+			check, err := m.check(ctx)
+			m.conditions[i] = current
+			if err != nil {
+				return nil, err
+			}
+			report.Reason += "; " + m.necessity(check, fmt.Sprintf(`as "%s"`, describeInputCondition(chain[0])))
+		}
+		m.result.Conditions = append(m.result.Conditions, report)
+	}
+
+	m.result.Game = m.game()
+	return m.result, nil
+}
+
+// minimizer - a puzzle part way through being minimized
+type minimizer struct {
+	input      *input.Game
+	opts       SearchOptions
+	conditions []input.Condition // the conditions, as loosened so far
+	kept       []bool            // whether each condition is still in the puzzle
+	result     *MinimizeResult
+}
+
+// the puzzle as it is now
+func (m *minimizer) game() *input.Game {
+	conditions := make([]input.Condition, 0, len(m.conditions))
+	for i, c := range m.conditions {
+		if m.kept[i] {
+			conditions = append(conditions, c)
+		}
+	}
+	return &input.Game{Cells: m.input.Cells, Conditions: &conditions, Dominoes: m.input.Dominoes}
+}
+
+// checks whether the puzzle as it is now has a unique solution
+func (m *minimizer) check(ctx context.Context) (UniquenessResult, error) {
+	game, err := ParseInputGame(m.game())
+	if err != nil {
+		return UniquenessResult{}, err
+	}
+	m.result.Checks++
+	check := CheckUniqueness(ctx, game, m.opts)
+	if check.Search.BudgetExceeded() && !m.result.BudgetExceeded() {
+		m.result.StopReason = check.Search.StopReason
+	}
+	return check, nil
+}
+
+// the ways a condition could be loosened, each a chain of steps from the condition as it is now - an exact sum can
+// become less than one more, or greater than one less, and those can keep moving until they stop meaning anything
+func looserConditions(c input.Condition) [][]input.Condition {
+	if c.Expression == nil || c.Operand == nil {
+		return nil
+	}
+	// any sum of the condition's cells is below this
+	bound := maxPipValue*len(c.Cells) + 1
+	lessThan := func(from int) []input.Condition {
+		chain := make([]input.Condition, 0)
+		for o := from; o < bound; o++ {
+			chain = append(chain, looserCondition(c, "<N", o))
+		}
+		return chain
+	}
+	greaterThan := func(from int) []input.Condition {
+		chain := make([]input.Condition, 0)
+		for o := from; o > 0; o-- {
+			chain = append(chain, looserCondition(c, ">N", o))
+		}
+		return chain
+	}
+
+	chains := make([][]input.Condition, 0, 2)
+	switch operand := *c.Operand; *c.Expression {
+	case "N":
+		chains = append(chains, lessThan(operand+1), greaterThan(operand-1))
+	case "<N":
+		chains = append(chains, lessThan(operand+1))
+	case ">N":
+		chains = append(chains, greaterThan(operand-1))
+	}
+	return slices.DeleteFunc(chains, func(chain []input.Condition) bool {
+		return len(chain) == 0
+	})
+}
+
+// a condition on the same cells with a different sum
+func looserCondition(c input.Condition, expression string, operand int) input.Condition {
+	return input.Condition{Expression: &expression, Operand: &operand, Cells: c.Cells}
+}
+
+// explains why a change to a condition can't be made, from the uniqueness check of the puzzle with it made
+func (m *minimizer) necessity(check UniquenessResult, change string) string {
+	switch check.Verdict {
+	case UniquenessVerdictAmbiguous:
+		return fmt.Sprintf("%s there is another solution, differing in cells %s", change, strings.Join(check.DifferingCells, ", "))
+	case UniquenessVerdictUnknown:
+		return fmt.Sprintf("%s the search %s before proving the solution unique", change, check.Search.StopReason.String())
+	case UniquenessVerdictUnique:
+		// only happens for a condition kept because an earlier check ran out of budget
+		return fmt.Sprintf("%s the solution is still unique, but an earlier check ran out of budget", change)
+	case UniquenessVerdictUnsolvable:
+		panic("taking away or loosening a condition can't make a puzzle unsolvable")
+	default:
+		panic("unhandled uniqueness verdict")
+	}
+}
+
+// describes a condition from the input file the same way the solver does
+func describeInputCondition(c input.Condition) string {
+	parsed, err := parseInputCondition(&c)
+	if err != nil {
+		return "invalid condition"
+	}
+	return parsed.String()
+}
@@ -0,0 +1,190 @@
+package solver
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// LocationOrder - how the pipeline's value search picks the next location of an arrangement to fill
+type LocationOrder int
+
+const (
+	// LocationOrderFewestChoices - at every step, fills the location with the fewest (domino, orientation) choices
+	// left given the dominoes remaining and the values its cells can still hold
+	LocationOrderFewestChoices LocationOrder = iota
+	// LocationOrderStatic - sorts the locations once by how many dominoes are blacklisted for them, and fills them in
+	// that order
+	LocationOrderStatic
+)
+
+var locationOrders = []LocationOrder{LocationOrderFewestChoices, LocationOrderStatic}
+
+func (o LocationOrder) String() string {
+	switch o {
+	case LocationOrderFewestChoices:
+		return "fewest-choices"
+	case LocationOrderStatic:
+		return "static"
+	default:
+		panic("unhandled location order")
+	}
+}
+
+// ParseLocationOrder - parses a location order from its name
+func ParseLocationOrder(s string) (LocationOrder, error) {
+	names := make([]string, 0, len(locationOrders))
+	for _, o := range locationOrders {
+		if s == o.String() {
+			return o, nil
+		}
+		names = append(names, o.String())
+	}
+	return 0, fmt.Errorf("%s is not a recognized location order - expected one of %s", s, strings.Join(names, ", "))
+}
+
+// ValueOrder - the order the pipeline's value search tries dominoes in a location
+type ValueOrder int
+
+const (
+	// ValueOrderInput - tries dominoes in the order they were listed in the input
+	ValueOrderInput ValueOrder = iota
+	// ValueOrderTightestSum - tries first the dominoes that leave the least slack in the sum conditions touching the
+	// location, so sums that are nearly met get met early
+	ValueOrderTightestSum
+)
+
+var valueOrders = []ValueOrder{ValueOrderInput, ValueOrderTightestSum}
+
+func (o ValueOrder) String() string {
+	switch o {
+	case ValueOrderInput:
+		return "input"
+	case ValueOrderTightestSum:
+		return "tightest-sum"
+	default:
+		panic("unhandled value order")
+	}
+}
+
+// ParseValueOrder - parses a value order from its name
+func ParseValueOrder(s string) (ValueOrder, error) {
+	names := make([]string, 0, len(valueOrders))
+	for _, o := range valueOrders {
+		if s == o.String() {
+			return o, nil
+		}
+		names = append(names, o.String())
+	}
+	return 0, fmt.Errorf("%s is not a recognized value order - expected one of %s", s, strings.Join(names, ", "))
+}
+
+// SearchOrder - the ordering heuristics used by the pipeline's value search, so different ones can be compared on
+// the same puzzles
+type SearchOrder struct {
+	Locations LocationOrder
+	Values    ValueOrder
+}
+
+// a domino kind laid into a location in a specific orientation
+type locationChoice struct {
+	kindIdx int
+	values  [2]int // values of the location's cell1 and cell2 respectively
+}
+
+// the (domino, orientation) choices that are still legal for a location
+func (l DominoArrangementLocation) choices(g *Game, unplacedDominoes dominoInventory, domains cellDomains) []locationChoice {
+	choices := make([]locationChoice, 0)
+	for kindIdx, kind := range g.dominoKinds {
+		if unplacedDominoes[kindIdx] == 0 || l.blacklistedDominoKinds.has(kindIdx) {
+			continue
+		}
+		for _, o := range kind.orientations() {
+			if domains[l.cell1.index].has(o[0]) && domains[l.cell2.index].has(o[1]) {
+				choices = append(choices, locationChoice{kindIdx: kindIdx, values: o})
+			}
+		}
+	}
+	return choices
+}
+
+// picks the index of the next location to fill and the choices to try there, in the order to try them
+func (o SearchOrder) next(
+	g *Game,
+	unfilledLocations []DominoArrangementLocation,
+	unplacedDominoes dominoInventory,
+	cellValues *boardValues,
+	conditions *conditionTracker,
+	domains cellDomains,
+) (int, []locationChoice) {
+	var next int
+	var choices []locationChoice
+	switch o.Locations {
+	case LocationOrderStatic:
+		next, choices = 0, unfilledLocations[0].choices(g, unplacedDominoes, domains)
+	case LocationOrderFewestChoices:
+		// ties go to the earliest location, which keeps the static order as a tie breaker
+		for i, l := range unfilledLocations {
+			c := l.choices(g, unplacedDominoes, domains)
+			if choices == nil || len(c) < len(choices) {
+				next, choices = i, c
+			}
+			if len(c) == 0 {
+				// a dead end - no point in looking any further
+				break
+			}
+		}
+	default:
+		panic("unhandled location order")
+	}
+
+	switch o.Values {
+	case ValueOrderInput:
+	case ValueOrderTightestSum:
+		l := unfilledLocations[next]
+		slack := make(map[locationChoice]int, len(choices))
+		for _, c := range choices {
+			cellValues.set(l.cell1.index, c.values[0])
+			cellValues.set(l.cell2.index, c.values[1])
+			conditions.fill(l.cell1, c.values[0])
+			conditions.fill(l.cell2, c.values[1])
+			slack[c] = conditions.sumSlack(l.cell1, cellValues, domains) + conditions.sumSlack(l.cell2, cellValues, domains)
+			conditions.unfill(l.cell1, c.values[0])
+			conditions.unfill(l.cell2, c.values[1])
+			cellValues.unset(l.cell1.index)
+			cellValues.unset(l.cell2.index)
+		}
+		slices.SortStableFunc(choices, func(l, r locationChoice) int {
+			return cmp.Compare(slack[l], slack[r])
+		})
+	default:
+		panic("unhandled value order")
+	}
+	return next, choices
+}
+
+// how far the sum conditions on a cell are from their targets, assuming the rest of their unfilled cells take the
+// values that would get them closest - 0 means a condition is met as tightly as possible
+func (t *conditionTracker) sumSlack(c *cell, cellValues *boardValues, domains cellDomains) int {
+	slack := 0
+	for _, cond := range c.applicableConditions {
+		minRest, maxRest := 0, 0
+		for _, cellIndex := range cond.cells {
+			if !cellValues.filled.has(cellIndex) {
+				minRest += domains[cellIndex].min()
+				maxRest += domains[cellIndex].max()
+			}
+		}
+		sum := t.sums[cond.index]
+		switch cond.expression {
+		case conditionExpSumEquals:
+			slack += max(cond.operand-(sum+maxRest), 0) + max((sum+minRest)-cond.operand, 0) + (maxRest - minRest)
+		case conditionExpSumLessThan:
+			slack += max(cond.operand-1-(sum+minRest), 0)
+		case conditionExpSumGreaterThan:
+			slack += max((sum+maxRest)-(cond.operand+1), 0)
+		}
+	}
+	return slack
+}
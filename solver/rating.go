@@ -0,0 +1,171 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DifficultyBucket - a rough label for how hard a puzzle is, like the NYT's own
+type DifficultyBucket int
+
+const (
+	// DifficultyEasy - solvable by deduction with the simpler techniques, in not too many steps
+	DifficultyEasy DifficultyBucket = iota
+	// DifficultyMedium - needs the harder deduction techniques, or a lot of steps with the simpler ones
+	DifficultyMedium
+	// DifficultyHard - needs trial and error, with enough searching behind it
+	DifficultyHard
+)
+
+var difficultyBuckets = []DifficultyBucket{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// ParseDifficultyBucket - parses a difficulty bucket from its name
+func ParseDifficultyBucket(s string) (DifficultyBucket, error) {
+	names := make([]string, 0, len(difficultyBuckets))
+	for _, b := range difficultyBuckets {
+		if s == b.String() {
+			return b, nil
+		}
+		names = append(names, b.String())
+	}
+	return 0, fmt.Errorf("%s is not a recognized difficulty - expected one of %s", s, strings.Join(names, ", "))
+}
+
+func (b DifficultyBucket) String() string {
+	switch b {
+	case DifficultyEasy:
+		return "easy"
+	case DifficultyMedium:
+		return "medium"
+	case DifficultyHard:
+		return "hard"
+	default:
+		panic("unhandled difficulty bucket")
+	}
+}
+
+// scores at or above which a puzzle is medium or hard, calibrated against the puzzles in test_files. Needing a search
+// alone is worth 35 points, so anything that needs one is at least medium, while a small puzzle that only needs the
+// techniques up to the only fitting domino (20 points) stays easy - handmade_easy.json scores 25.9 and the tutorial
+// 27.6. The hard line sits between the highest NYT medium (2025_08_26_medium.json at 51.5) and the lowest NYT hard
+// (2025_08_27_hard.json at 59.5).
+const (
+	mediumDifficultyScore = 35
+	hardDifficultyScore   = 55
+)
+
+// RatingComponent - one metric that went into a difficulty rating, and the points it added
+type RatingComponent struct {
+	Name   string
+	Value  string
+	Points float64
+}
+
+// Rating - how hard a puzzle is, along with the metrics the score was worked out from
+type Rating struct {
+	Score      float64
+	Bucket     DifficultyBucket
+	Components []RatingComponent
+	// why the searches behind the rating ended - if they were cut short, the rating is only partial and likely lower
+	// than it should be
+	StopReason StopReason
+}
+
+// Partial - whether the searches behind the rating were cut short, so it doesn't count everything
+func (r Rating) Partial() bool {
+	return r.StopReason != StopReasonExhausted
+}
+
+func (r Rating) String() string {
+	out := fmt.Sprintf("Difficulty %.1f (%s)\n", r.Score, r.Bucket)
+	if r.Partial() {
+		out = fmt.Sprintf("Difficulty at least %.1f (%s) - partial, the search %s\n", r.Score, r.Bucket, r.StopReason)
+	}
+	for _, c := range r.Components {
+		out += fmt.Sprintf("  %-18s %-22s %+6.1f\n", c.Name, c.Value, c.Points)
+	}
+	return out
+}
+
+// RatePuzzle - rates how hard a game is to solve, combining how a person would get on (the hardest deduction
+// technique needed, and how many steps) with how hard it is to search (tilings of the board, search nodes,
+// backtracks, and how deep the search still had to guess). The search uses the pipeline engine with the ordering
+// and memory settings in the options, on a single worker so the same puzzle always gets the same rating. It runs to
+// the end unless the options' node limit or timeout cut it short, in which case the rating is only partial.
+func RatePuzzle(ctx context.Context, game *Game, opts SearchOptions) Rating {
+	if game == nil {
+		panic("nil game")
+	}
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Rating puzzle difficulty...")
+
+	// the timeout covers the whole rating, and every search is kept to one worker
+	if opts.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, opts.Timeout)
+		defer cancelTimeout()
+		opts.Timeout = 0
+	}
+	opts.Workers = 1
+
+	// how a person would get on
+	deduction := Deduce(ctx, game, opts)
+
+	// how many ways the board can be tiled with the dominoes
+	tilingBudget := NewSearchBudget(opts.MaxNodes)
+	arrangements := make(chan DominoArrangement)
+	go func() {
+		GetDominoArrangements(ctx, game, tilingBudget, arrangements)
+		close(arrangements)
+	}()
+	for range arrangements {
+	}
+
+	// how hard it is to search
+	opts.Engine = EnginePipeline
+	opts.MaxSolutions = 0
+	search := Search(ctx, game, opts)
+
+	// each metric adds points, growing slowly for the ones that can get very big
+	logPoints := func(n int64) float64 {
+		return math.Log2(float64(n + 1))
+	}
+	hardest := deduction.HardestTechnique()
+	if deduction.UsedSearch {
+		hardest = TechniqueSearch
+	}
+	rating := Rating{Components: []RatingComponent{
+		{"hardest technique", hardest.String(), 5 * float64(hardest)},
+		{"deduction steps", fmt.Sprint(len(deduction.Steps)), 0.2 * float64(len(deduction.Steps))},
+		{"tilings", fmt.Sprint(tilingBudget.Arrangements()), 2 * logPoints(tilingBudget.Arrangements())},
+		{"search nodes", fmt.Sprint(search.Nodes), logPoints(search.Nodes)},
+		{"backtracks", fmt.Sprint(search.Backtracks), 2 * logPoints(search.Backtracks)},
+		{"branching depth", fmt.Sprint(search.BranchingDepth), float64(search.BranchingDepth)},
+	}}
+	for _, c := range rating.Components {
+		rating.Score += c.Points
+	}
+	switch {
+	case deduction.StopReason != StopReasonExhausted && deduction.StopReason != StopReasonSolutionLimit:
+		rating.StopReason = deduction.StopReason
+	case tilingBudget.Exceeded():
+		rating.StopReason = StopReasonNodeLimit
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) && search.StopReason == StopReasonExhausted:
+		rating.StopReason = StopReasonTimeout
+	default:
+		rating.StopReason = search.StopReason
+	}
+	switch {
+	case rating.Score >= hardDifficultyScore:
+		rating.Bucket = DifficultyHard
+	case rating.Score >= mediumDifficultyScore:
+		rating.Bucket = DifficultyMedium
+	default:
+		rating.Bucket = DifficultyEasy
+	}
+	return rating
+}
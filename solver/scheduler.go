@@ -0,0 +1,153 @@
+package solver
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// A work stealing scheduler for splitting a search tree across workers, GOMAXPROCS of them unless told otherwise.
+//
+// Every worker has its own deque of tasks. A worker takes the newest task from its own deque (staying depth first,
+// like plain recursion), and when its deque is empty, steals the oldest task from the fullest deque of another
+// worker - the oldest tasks are the shallowest, so they tend to be the largest subtrees.
+//
+// Searches only split off a subtree as a task while another worker is idle and the subtree is shallow enough to be
+// worth the copy. Everything else is explored inline by plain recursion, so the number of queued tasks (and the
+// memory they hold) stays around the number of workers no matter how big the search tree is.
+
+// maximum depth at which searches split off subtrees as tasks
+const maxSplitDepth = 8
+
+// searchTask - a subtree of a search, explored by whichever worker runs it
+type searchTask func(w *schedulerWorker)
+
+type scheduler struct {
+	mu      sync.Mutex
+	wake    *sync.Cond
+	deques  [][]searchTask // one deque per worker, newest task last
+	running int            // tasks being run right now
+	// read without the lock to decide whether to split, so kept atomically as well
+	queued atomic.Int64 // tasks sitting in deques
+	idle   atomic.Int64 // workers waiting for a task
+	// stats for debug printing
+	tasksRun, steals int
+}
+
+// schedulerWorker - the handle a running task uses to split off more tasks
+type schedulerWorker struct {
+	id int
+	s  *scheduler
+}
+
+// runScheduled - runs the root task and everything it splits off across a number of workers (0 for GOMAXPROCS),
+// returning once all of it is done. A single worker never splits, so it explores the tree in the same order every
+// time.
+func runScheduled(numWorkers int, root searchTask) {
+	if numWorkers <= 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	s := &scheduler{deques: make([][]searchTask, numWorkers)}
+	s.wake = sync.NewCond(&s.mu)
+	s.push(0, root)
+
+	wg := new(sync.WaitGroup)
+	for id := range numWorkers {
+		wg.Go(func() {
+			w := &schedulerWorker{id: id, s: s}
+			for {
+				task, ok := s.next(id)
+				if !ok {
+					return
+				}
+				task(w)
+				s.finish()
+			}
+		})
+	}
+	wg.Wait()
+	debugPrint(fmt.Printf, "Scheduler ran %d tasks on %d workers (%d stolen)\n", s.tasksRun, numWorkers, s.steals)
+}
+
+// adds a task to the end of a worker's deque
+func (s *scheduler) push(worker int, t searchTask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deques[worker] = append(s.deques[worker], t)
+	s.queued.Add(1)
+	s.wake.Signal()
+}
+
+// waits for the next task for a worker, returning false once there is no work left anywhere
+func (s *scheduler) next(worker int) (searchTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		// newest task from the worker's own deque
+		if q := s.deques[worker]; len(q) > 0 {
+			t := q[len(q)-1]
+			q[len(q)-1] = nil
+			s.deques[worker] = q[:len(q)-1]
+			return s.take(t), true
+		}
+
+		// oldest task from whoever has the most queued up
+		victim := -1
+		for i, q := range s.deques {
+			if len(q) > 0 && (victim == -1 || len(q) > len(s.deques[victim])) {
+				victim = i
+			}
+		}
+		if victim != -1 {
+			q := s.deques[victim]
+			t := q[0]
+			q[0] = nil
+			s.deques[victim] = q[1:]
+			s.steals++
+			return s.take(t), true
+		}
+
+		// nothing queued and nothing running that could add more - all done
+		if s.running == 0 {
+			s.wake.Broadcast()
+			return nil, false
+		}
+
+		s.idle.Add(1)
+		s.wake.Wait()
+		s.idle.Add(-1)
+	}
+}
+
+// bookkeeping for a task leaving a deque, must hold the lock
+func (s *scheduler) take(t searchTask) searchTask {
+	s.queued.Add(-1)
+	s.running++
+	s.tasksRun++
+	return t
+}
+
+// marks a task as done, waking everyone up if it was the last of the work
+func (s *scheduler) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	if s.running == 0 && s.queued.Load() == 0 {
+		s.wake.Broadcast()
+	}
+}
+
+// shouldSplit - whether a subtree at this depth should be handed off as a task rather than explored inline, which
+// is only worth it while other workers have nothing to do. A nil worker (searching without a scheduler) never splits.
+func (w *schedulerWorker) shouldSplit(depth int) bool {
+	if w == nil || depth > maxSplitDepth {
+		return false
+	}
+	return w.s.queued.Load() < w.s.idle.Load()
+}
+
+// spawn - hands a subtree off to the scheduler, to be run by this worker or stolen by another
+func (w *schedulerWorker) spawn(t searchTask) {
+	w.s.push(w.id, t)
+}
@@ -0,0 +1,253 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Engine - a strategy for searching for solutions
+type Engine int
+
+const (
+	// EnginePipeline - calculates every domino arrangement first, then tries domino values on each one
+	EnginePipeline Engine = iota
+	// EngineUnified - tiles the board and places domino values in a single search
+	EngineUnified
+	// EngineExactCover - solves the puzzle as an exact cover problem using Dancing Links
+	EngineExactCover
+	// EngineDecompose - solves independent parts of the board separately, then combines them by sharing out the
+	// dominoes
+	EngineDecompose
+)
+
+var engines = []Engine{EnginePipeline, EngineUnified, EngineExactCover, EngineDecompose}
+
+func (e Engine) String() string {
+	switch e {
+	case EnginePipeline:
+		return "pipeline"
+	case EngineUnified:
+		return "unified"
+	case EngineExactCover:
+		return "dlx"
+	case EngineDecompose:
+		return "decompose"
+	default:
+		panic("unhandled engine")
+	}
+}
+
+// ParseEngine - parses an engine from its name
+func ParseEngine(s string) (Engine, error) {
+	names := make([]string, 0, len(engines))
+	for _, e := range engines {
+		if s == e.String() {
+			return e, nil
+		}
+		names = append(names, e.String())
+	}
+	return 0, fmt.Errorf("%s is not a recognized solving engine - expected one of %s", s, strings.Join(names, ", "))
+}
+
+// StopReason - why a search ended
+type StopReason int
+
+const (
+	// StopReasonExhausted - every possibility was searched
+	StopReasonExhausted StopReason = iota
+	// StopReasonSolutionLimit - enough solutions were found
+	StopReasonSolutionLimit
+	// StopReasonTimeout - the search ran out of time
+	StopReasonTimeout
+	// StopReasonNodeLimit - the search visited as many nodes as it was allowed to
+	StopReasonNodeLimit
+	// StopReasonCancelled - the caller cancelled the search
+	StopReasonCancelled
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopReasonExhausted:
+		return "searched every possibility"
+	case StopReasonSolutionLimit:
+		return "found enough solutions"
+	case StopReasonTimeout:
+		return "ran out of time"
+	case StopReasonNodeLimit:
+		return "hit the search node limit"
+	case StopReasonCancelled:
+		return "was cancelled"
+	default:
+		panic("unhandled stop reason")
+	}
+}
+
+// SearchOptions - settings for Search
+type SearchOptions struct {
+	Engine       Engine
+	Identity     SolutionIdentity // what makes two solutions different
+	MaxSolutions int              // stop once this many solutions are found (0 for no limit)
+	Timeout      time.Duration    // stop after this long (0 for no limit)
+	MaxNodes     int64            // stop after visiting this many search nodes (0 for no limit)
+	Order        SearchOrder      // ordering heuristics for the pipeline engine's value search
+	// memory cap in bytes for the pipeline engine's transposition table of dead end states (0 for no table)
+	TranspositionMemory int64
+	// workers the pipeline and unified engines split the search across (0 for GOMAXPROCS) - with a single worker, the
+	// search finds solutions in the same order and visits the same nodes every time
+	Workers int
+}
+
+// SearchResult - the outcome of a search, including how far it got if it was cut short
+type SearchResult struct {
+	Solutions    []Solution // distinct valid solutions found, even if the search was cut short
+	StopReason   StopReason
+	Nodes        int64 // search nodes visited
+	Arrangements int64 // complete domino arrangements found (pipeline engine only)
+	// partial tilings abandoned before value search because they couldn't be filled with the dominoes (pipeline
+	// engine only)
+	TilingsPruned int64
+	Transposition TranspositionStats // how much the transposition table helped (pipeline engine only)
+	Backtracks    int64              // domino placements taken back (pipeline engine only)
+	// the deepest placement that still had to choose between dominoes (pipeline engine only)
+	BranchingDepth int
+	Elapsed        time.Duration
+}
+
+// Exhaustive - whether every possibility was searched, so the solutions found are all the solutions there are
+func (r SearchResult) Exhaustive() bool {
+	return r.StopReason == StopReasonExhausted
+}
+
+// BudgetExceeded - whether the search ran out of time or nodes before it could finish
+func (r SearchResult) BudgetExceeded() bool {
+	return r.StopReason == StopReasonTimeout || r.StopReason == StopReasonNodeLimit
+}
+
+// Search - looks for valid solutions with the chosen engine, dropping repeats, until the search space is exhausted
+// or one of the limits in the options is hit
+func Search(ctx context.Context, game *Game, opts SearchOptions) SearchResult {
+	if game == nil {
+		panic("nil game")
+	}
+	startTime := time.Now()
+
+	// cancelling stops every stage of the search, once enough solutions have been found
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if opts.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, opts.Timeout)
+		defer cancelTimeout()
+	}
+	budget := NewSearchBudget(opts.MaxNodes)
+	table := newTranspositionTable(opts.TranspositionMemory)
+
+	var validSolutionChan <-chan Solution
+	switch opts.Engine {
+	case EnginePipeline:
+		validSolutionChan = searchPipeline(ctx, game, budget, table, opts.Order, opts.Workers)
+	case EngineUnified:
+		validSolutionChan = searchUnified(ctx, game, budget, opts.Workers)
+	case EngineExactCover:
+		validSolutionChan = searchExactCover(ctx, game, budget)
+	case EngineDecompose:
+		validSolutionChan = searchDecomposed(ctx, game, budget)
+	default:
+		panic("unhandled engine")
+	}
+
+	// keep reading until the search winds down, even after cancelling, so no stage is left blocked
+	result := SearchResult{Solutions: make([]Solution, 0)}
+	deduplicator := NewSolutionDeduplicator(opts.Identity)
+	solutionLimitHit := false
+	for s := range validSolutionChan {
+		if solutionLimitHit || !deduplicator.Add(s) {
+			continue
+		}
+		result.Solutions = append(result.Solutions, s)
+		if opts.MaxSolutions > 0 && len(result.Solutions) == opts.MaxSolutions {
+			solutionLimitHit = true
+			cancel()
+		}
+	}
+
+	// figure out why the search ended
+	switch {
+	case solutionLimitHit:
+		result.StopReason = StopReasonSolutionLimit
+	case budget.Exceeded():
+		result.StopReason = StopReasonNodeLimit
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.StopReason = StopReasonTimeout
+	case ctx.Err() != nil:
+		result.StopReason = StopReasonCancelled
+	default:
+		result.StopReason = StopReasonExhausted
+	}
+	result.Nodes = budget.Nodes()
+	result.Arrangements = budget.Arrangements()
+	result.TilingsPruned = budget.TilingsPruned()
+	result.Transposition = table.Stats()
+	result.Backtracks = budget.Backtracks()
+	result.BranchingDepth = budget.BranchingDepth()
+	result.Elapsed = time.Since(startTime)
+	return result
+}
+
+// calculates domino arrangements, then finds values for each arrangement as soon as it is found - both searches are
+// split across the work stealing scheduler. Conditions are checked as the values are placed, so there is no need to
+// check the solutions again afterwards.
+func searchPipeline(ctx context.Context, game *Game, budget *SearchBudget, table *transpositionTable, order SearchOrder, workers int) <-chan Solution {
+	validSolutionChan := make(chan Solution)
+	go func() {
+		runScheduled(workers, func(w *schedulerWorker) {
+			findDominoArrangements(ctx, game, budget, w, game.inPlayCells, []DominoArrangementLocation{}, newDominoMatching(game), func(w *schedulerWorker, a DominoArrangement) {
+				fillDominoArrangement(ctx, game, budget, w, table, order, &a, func(s Solution) {
+					sendOrCancel(ctx, validSolutionChan, s)
+				})
+			})
+		})
+		debugPrint(fmt.Printf, "Tiling placed %d locations by forced moves and %d by branching\n", budget.ForcedLocations(), budget.BranchedLocations())
+		close(validSolutionChan)
+	}()
+	return validSolutionChan
+}
+
+// tiles the board and assigns values in a single search, split across the work stealing scheduler
+func searchUnified(ctx context.Context, game *Game, budget *SearchBudget, workers int) <-chan Solution {
+	validSolutionChan := make(chan Solution)
+	go func() {
+		runScheduled(workers, func(w *schedulerWorker) {
+			searchUnifiedFrom(ctx, game, budget, w, func(s Solution) {
+				sendOrCancel(ctx, validSolutionChan, s)
+			})
+		})
+		close(validSolutionChan)
+	}()
+	return validSolutionChan
+}
+
+// solves the game as an exact cover problem using Dancing Links - the linked matrix is shared mutable state, so this
+// engine stays on a single goroutine
+func searchExactCover(ctx context.Context, game *Game, budget *SearchBudget) <-chan Solution {
+	validSolutionChan := make(chan Solution)
+	go func() {
+		GetSolutionsExactCover(ctx, game, budget, validSolutionChan)
+		close(validSolutionChan)
+	}()
+	return validSolutionChan
+}
+
+// fills each independent sub-puzzle of the board on its own and combines the results - kept on a single goroutine
+// like the exact cover engine
+func searchDecomposed(ctx context.Context, game *Game, budget *SearchBudget) <-chan Solution {
+	validSolutionChan := make(chan Solution)
+	go func() {
+		GetSolutionsDecomposed(ctx, game, budget, validSolutionChan)
+		close(validSolutionChan)
+	}()
+	return validSolutionChan
+}
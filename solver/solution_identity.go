@@ -0,0 +1,146 @@
+package solver
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SolutionIdentity - decides when two solutions count as the same solution
+type SolutionIdentity int
+
+const (
+	// SolutionIdentityPipGrid - solutions are the same if every cell holds the same value, no matter which
+	// dominoes cover which cells
+	SolutionIdentityPipGrid SolutionIdentity = iota
+	// SolutionIdentityDominoLayout - solutions are the same if the same pairs of cells are covered by dominoes
+	// holding the same values (swapping identical dominoes or flipping a double changes nothing)
+	SolutionIdentityDominoLayout
+)
+
+func (i SolutionIdentity) String() string {
+	switch i {
+	case SolutionIdentityPipGrid:
+		return "grid"
+	case SolutionIdentityDominoLayout:
+		return "layout"
+	default:
+		panic("unhandled solution identity")
+	}
+}
+
+// ParseSolutionIdentity - parses a solution identity from its name ("grid" or "layout")
+func ParseSolutionIdentity(s string) (SolutionIdentity, error) {
+	for _, i := range []SolutionIdentity{SolutionIdentityPipGrid, SolutionIdentityDominoLayout} {
+		if s == i.String() {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf(`%s is not a recognized solution identity - expected "grid" or "layout"`, s)
+}
+
+// the placement with its cells in board order (top to bottom, then left to right) - which is cell index order
+func (p DominoPlacement) canonical() DominoPlacement {
+	if p.cell1.index > p.cell2.index {
+		p.cell1, p.cell2 = p.cell2, p.cell1
+		p.cell1Value, p.cell2Value = p.cell2Value, p.cell1Value
+	}
+	return p
+}
+
+// Canonical - returns a form of the solution that is equal for any two solutions that are the same under the identity,
+// independent of the order dominoes were placed in or the orientation they were found in
+func (s Solution) Canonical(identity SolutionIdentity) string {
+	// built by hand rather than with fmt, since every solution found goes through here
+	var sb strings.Builder
+	switch identity {
+	case SolutionIdentityPipGrid:
+		type cellValue struct {
+			c *cell
+			v int
+		}
+		cellValues := make([]cellValue, 0, 2*len(s.dominoPlacements))
+		for _, p := range s.dominoPlacements {
+			cellValues = append(cellValues, cellValue{p.cell1, p.cell1Value}, cellValue{p.cell2, p.cell2Value})
+		}
+		slices.SortFunc(cellValues, func(l, r cellValue) int {
+			return cmp.Compare(l.c.index, r.c.index)
+		})
+		for i, cv := range cellValues {
+			if i > 0 {
+				sb.WriteByte(';')
+			}
+			sb.WriteString(cv.c.identifier())
+			sb.WriteByte('=')
+			sb.WriteString(strconv.Itoa(cv.v))
+		}
+	case SolutionIdentityDominoLayout:
+		placements := make([]DominoPlacement, 0, len(s.dominoPlacements))
+		for _, p := range s.dominoPlacements {
+			placements = append(placements, p.canonical())
+		}
+		slices.SortFunc(placements, func(l, r DominoPlacement) int {
+			return cmp.Compare(l.cell1.index, r.cell1.index)
+		})
+		for i, p := range placements {
+			if i > 0 {
+				sb.WriteByte(';')
+			}
+			sb.WriteString(p.cell1.identifier())
+			sb.WriteByte('-')
+			sb.WriteString(p.cell2.identifier())
+			sb.WriteByte('=')
+			sb.WriteString(strconv.Itoa(p.cell1Value))
+			sb.WriteByte('|')
+			sb.WriteString(strconv.Itoa(p.cell2Value))
+		}
+	default:
+		panic("unhandled solution identity")
+	}
+	return sb.String()
+}
+
+// Hash - a stable hash of the solution's canonical form under the identity
+func (s Solution) Hash(identity SolutionIdentity) uint64 {
+	return hashCanonicalSolution(s.Canonical(identity))
+}
+
+func hashCanonicalSolution(canonical string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(canonical))
+	return h.Sum64()
+}
+
+// SolutionDeduplicator - remembers the solutions it has seen so repeats can be dropped. Safe for concurrent use.
+type SolutionDeduplicator struct {
+	identity SolutionIdentity
+	mu       sync.Mutex
+	seen     map[uint64][]string // canonical forms by hash, in case two different solutions share a hash
+}
+
+// NewSolutionDeduplicator - creates a deduplicator that treats solutions as the same under the identity
+func NewSolutionDeduplicator(identity SolutionIdentity) *SolutionDeduplicator {
+	return &SolutionDeduplicator{
+		identity: identity,
+		seen:     make(map[uint64][]string),
+	}
+}
+
+// Add - records the solution, returning false if the same solution was already added
+func (d *SolutionDeduplicator) Add(s Solution) bool {
+	canonical := s.Canonical(d.identity)
+	hash := hashCanonicalSolution(canonical)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if slices.Contains(d.seen[hash], canonical) {
+		debugPrint(fmt.Printf, "Dropping repeat solution (by %s)...\n", d.identity.String())
+		return false
+	}
+	d.seen[hash] = append(d.seen[hash], canonical)
+	return true
+}
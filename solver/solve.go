@@ -1,19 +1,18 @@
 package solver
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"maps"
 	"slices"
 	"strings"
 )
 
 // DominoPlacement - the specific location and orientation of a domino in a Solution
 type DominoPlacement struct {
-	cell1Identifier string
-	cell1Value      int
-	cell2Identifier string
-	cell2Value      int
+	cell1      *cell
+	cell1Value int
+	cell2      *cell
+	cell2Value int
 	// string for pretty printing the domino
 	printString string
 }
@@ -21,7 +20,7 @@ type DominoPlacement struct {
 func (p DominoPlacement) String() string {
 	return fmt.Sprintf(
 		"Domino %s placed with %d in Cell %s & %d in Cell %s\n",
-		p.printString, p.cell1Value, p.cell1Identifier, p.cell2Value, p.cell2Identifier,
+		p.printString, p.cell1Value, p.cell1.identifier(), p.cell2Value, p.cell2.identifier(),
 	)
 }
 
@@ -38,56 +37,95 @@ func (s Solution) String() string {
 	return out
 }
 
-func getCellValuesFromPlacements(placements *[]DominoPlacement) map[string] /* cell identifier */ int /*cell value */ {
+func getCellValuesFromPlacements(numCells int, placements *[]DominoPlacement) *boardValues {
 	if placements == nil {
 		panic("nil placements")
 	}
-	m := make(map[string]int)
+	b := newBoardValues(numCells)
 	for _, p := range *placements {
-		m[p.cell1Identifier] = p.cell1Value
-		m[p.cell2Identifier] = p.cell2Value
+		b.set(p.cell1.index, p.cell1Value)
+		b.set(p.cell2.index, p.cell2Value)
 	}
-	return m
+	return b
 }
 
 // GetPossibleSolutionsForArrangement - finds different potential solutions to check.
 // Ideally, a lot of incorrect solution paths are eliminated here with early condition checks.
-func GetPossibleSolutionsForArrangement(game *Game, dominoArrangement *DominoArrangement, outPossibleSolutions chan<- Solution) {
+// Stops early if the context is cancelled or the budget runs out.
+func GetPossibleSolutionsForArrangement(
+	ctx context.Context,
+	game *Game,
+	budget *SearchBudget,
+	order SearchOrder,
+	dominoArrangement *DominoArrangement,
+	outPossibleSolutions chan<- Solution,
+) {
 	if game == nil {
 		panic("nil game")
 	}
 	if dominoArrangement == nil {
 		panic("nil arrangement")
 	}
+
+	fillDominoArrangement(ctx, game, budget, nil, nil, order, dominoArrangement, func(s Solution) {
+		if sendOrCancel(ctx, outPossibleSolutions, s) {
+			debugPrint(fmt.Println, "All dominoes placed and possible solution added...")
+		}
+	})
+}
+
+// finds possible solutions for an arrangement, handing each one to emit - splits the search across the scheduler
+// if given a worker, and skips states the transposition table already knows are dead ends if given one
+func fillDominoArrangement(
+	ctx context.Context,
+	game *Game,
+	budget *SearchBudget,
+	worker *schedulerWorker,
+	table *transpositionTable,
+	order SearchOrder,
+	dominoArrangement *DominoArrangement,
+	emit func(Solution),
+) {
 	debugPrint(fmt.Println, strings.Repeat("*", 64))
 	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
 	debugPrint(fmt.Println, "Calculating possible solutions using arrangement...")
-	debugPrint(fmt.Println, dominoArrangement.String())
+	debugPrint(fmt.Println, dominoArrangement)
 
 	// track locations that have not been filled with a domino yet
 	unfilledLocations := make([]DominoArrangementLocation, len(dominoArrangement.locations))
 	copy(unfilledLocations, dominoArrangement.locations)
 
 	// sort so the location with the fewest possibilities is evaluated first, reducing
-	// the breadth of the decision tree
+	// the breadth of the decision tree - with dynamic location ordering, this just breaks ties
 	//
 	// for one puzzle, starting with the least # of dominoes vs. most reduced solve time from 90s to 6s
 	slices.SortFunc(unfilledLocations, func(l, r DominoArrangementLocation) int {
-		return len(*r.blacklistedDominoIDs) - len(*l.blacklistedDominoIDs)
+		return r.blacklistedDominoKinds.size() - l.blacklistedDominoKinds.size()
 	})
 
 	// track unplaced and placed dominoes as time progresses
-	unplacedDominoes := make(map[string]*domino)
-	for _, d := range game.dominoes {
-		unplacedDominoes[d.identifier] = d
-	}
+	unplacedDominoes := game.newDominoInventory()
 	placementsSoFar := make([]DominoPlacement, 0)
+	cellValues := newBoardValues(len(game.cells))
+	conditions := newConditionTracker(game)
+
+	// track the values each cell could still hold
+	domains := game.initialCellDomains()
+
+	// match every location to a domino that could go there - an arrangement with no such matching can't be filled
+	matching, ok := newFillMatching(game, unfilledLocations, domains)
+	if !ok {
+		debugPrint(fmt.Println, "Arrangement can't be matched to the dominoes - skipping it...")
+		return
+	}
 
 	// start placing dominoes
-	placeDomino(game, unfilledLocations, unplacedDominoes, placementsSoFar, outPossibleSolutions)
+	placeDomino(ctx, game, budget, worker, table, order, unfilledLocations, unplacedDominoes, placementsSoFar, cellValues, conditions, domains, matching, emit)
 }
 
-// CheckSolution - returns if a possible solution successfully met all the conditions to solve the puzzle
+// CheckSolution - returns if a possible solution successfully met all the conditions to solve the puzzle.
+// The search engines already guarantee this for every solution they find, so it is only needed for solutions from
+// elsewhere.
 func CheckSolution(game *Game, solution *Solution) bool {
 	if game == nil {
 		panic("nil game")
@@ -98,10 +136,10 @@ func CheckSolution(game *Game, solution *Solution) bool {
 	debugPrint(fmt.Println, strings.Repeat("*", 64))
 	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
 	debugPrint(fmt.Println, "Checking possible solution...")
-	debugPrint(fmt.Println, solution.String())
+	debugPrint(fmt.Println, solution)
 
 	// check each condition, early returning if one fails
-	cellValues := getCellValuesFromPlacements(&solution.dominoPlacements)
+	cellValues := getCellValuesFromPlacements(len(game.cells), &solution.dominoPlacements)
 	for _, cond := range game.conditions {
 		if ok, err := cond.check(cellValues); err != nil {
 			panic("very unexpected error checking condition")
@@ -116,19 +154,41 @@ func CheckSolution(game *Game, solution *Solution) bool {
 }
 
 // recursively places dominoes on the game board, testing along the way until a solution is reached
+//
+// every condition is checked as its cells are filled, so every solution reached is valid
+//
+// the unfilled locations are kept matched to the unplaced dominoes as they are placed, so a placement that leaves
+// some location without any domino that could go there is abandoned straight away, instead of once the search gets
+// down to that location
+//
+// returns whether the state was searched in full without finding any solutions - the same state can be reached by
+// placing the same dominoes in a different order (or in another arrangement sharing the locations left), so dead
+// states are recorded in the transposition table and skipped when they come up again
 func placeDomino(
+	ctx context.Context,
 	game *Game,
+	budget *SearchBudget,
+	worker *schedulerWorker,
+	table *transpositionTable,
+	order SearchOrder,
 	unfilledLocations []DominoArrangementLocation,
-	unplacedDominoes map[string]*domino,
+	unplacedDominoes dominoInventory,
 	placementsSoFar []DominoPlacement,
-	outPossibleSolutions chan<- Solution,
-) {
+	cellValues *boardValues, // the values of placementsSoFar
+	conditions *conditionTracker,
+	domains cellDomains,
+	matching *dominoMatching, // matches unfilledLocations to unplacedDominoes
+	emit func(Solution),
+) (dead bool) {
 	if game == nil {
 		panic("nil board")
 	}
-	if len(unfilledLocations) != len(unplacedDominoes) {
+	if len(unfilledLocations) != unplacedDominoes.total() {
 		panic("mismatch between number of dominoes and places to put them")
 	}
+	if ctx.Err() != nil || !budget.spendNode() {
+		return false
+	}
 
 	// base case - all locations have been filled with a
 	if len(unfilledLocations) == 0 {
@@ -138,89 +198,109 @@ func placeDomino(
 		newSolution := Solution{
 			dominoPlacements: placementsCopy,
 		}
-		outPossibleSolutions <- newSolution
-		debugPrint(fmt.Println, "All dominoes placed and possible solution added...")
-		return
+		emit(newSolution)
+		return false
 	}
 
-	// check for violated conditions before moving on
-	{
-		cellValuesSoFar := getCellValuesFromPlacements(&placementsSoFar)
-		for _, cond := range game.conditions {
-			ok, err := cond.check(cellValuesSoFar)
-			if err != nil {
-				if errors.Is(err, errConditionNotReadyToCheck) {
-					// condition just isn't ready to evaluate yet - move on with placing a domino
-					continue
-				}
-				panic("very unexpected error checking condition" + err.Error())
-			}
-			if !ok {
-				// condition failed! abort this path
-				return
-			}
+	hash := game.zobrist.locationsHash(unfilledLocations, unplacedDominoes, conditions)
+	if _, ok := table.lookup(hash); ok {
+		debugPrint(fmt.Println, "state already known to be a dead end...")
+		return true
+	}
 
-		}
+	// pick where to place a domino next, and which dominoes to try there - identical dominoes are interchangeable,
+	// so only one of each kind is tried, in both orientations if they are different
+	nextIdx, choices := order.next(game, unfilledLocations, unplacedDominoes, cellValues, conditions, domains)
+	nextLocation := unfilledLocations[nextIdx]
+	remainingLocations := slices.Concat(unfilledLocations[:nextIdx], unfilledLocations[nextIdx+1:])
+	if len(choices) == 0 {
+		debugPrint(fmt.Printf, "no dominoes left can go in location %s...\n", nextLocation)
+		return true
+	}
+	if len(choices) > 1 {
+		budget.recordBranch(len(placementsSoFar) + 1)
 	}
 
-	nextLocation := unfilledLocations[0]
-	remainingLocations := make([]DominoArrangementLocation, len(unfilledLocations[1:]))
-	copy(remainingLocations, unfilledLocations[1:])
+	// whether any solutions were found below here, or might have been by another worker
+	alive := false
+	for _, choice := range choices {
+		kindIdx, o := choice.kindIdx, choice.values
+		nextDomino := game.dominoKinds[kindIdx]
+		debugPrint(fmt.Printf, "placing domino %s in location %s as %d & %d...\n", nextDomino, nextLocation, o[0], o[1])
 
-	// try all dominoes
-	for _, nextDomino := range slices.Collect(maps.Values(unplacedDominoes)) {
-		// skip the domino if it is blacklisted for the location
-		if _, blacklisted := (*nextLocation.blacklistedDominoIDs)[nextDomino.identifier]; blacklisted {
-			continue
+		// generate the next placement
+		placement := &DominoPlacement{
+			cell1:       nextLocation.cell1,
+			cell1Value:  o[0],
+			cell2:       nextLocation.cell2,
+			cell2Value:  o[1],
+			printString: nextDomino.String(),
 		}
 
-		// try both orientations if they are different too
-		type orientation struct {
-			cell1Val, cell2Val int
-		}
-		orientations := []orientation{
-			{
-				cell1Val: nextDomino.val1,
-				cell2Val: nextDomino.val2,
-			},
-		}
-		if nextDomino.val1 != nextDomino.val2 {
-			orientations = append(orientations, orientation{
-				cell1Val: nextDomino.val2,
-				cell2Val: nextDomino.val1,
-			})
-		} else {
-			debugPrint(fmt.Printf, "not checking reverse orientation of domino %s in location %s...\n", nextDomino.String(), nextLocation.String())
-		}
+		// track the placement
+		placementsSoFar = append(placementsSoFar, *placement)
+		cellValues.set(nextLocation.cell1.index, o[0])
+		cellValues.set(nextLocation.cell2.index, o[1])
+		conditions.fill(nextLocation.cell1, o[0])
+		conditions.fill(nextLocation.cell2, o[1])
 
-		for i, o := range orientations {
-			if i == 0 {
-				debugPrint(fmt.Printf, "placing domino %s in location %s...\n", nextDomino.String(), nextLocation.String())
-			} else {
-				debugPrint(fmt.Printf, "placing domino %s in location %s (reverse orientation)...\n", nextDomino.String(), nextLocation.String())
-			}
+		// only the conditions on the two cells could have been broken by the placement
+		if conditions.holds(nextLocation.cell1) && conditions.holds(nextLocation.cell2) {
+			// narrow the domains to the placed values and see what that does to the other cells - if a condition's
+			// target can no longer be reached, there is no point in going any deeper
+			nextDomains := slices.Clone(domains)
+			nextDomains[nextLocation.cell1.index] = singleValueDomain(o[0])
+			nextDomains[nextLocation.cell2.index] = singleValueDomain(o[1])
+			ok := game.propagateFromCells(cellValues, nextDomains, nextLocation.cell1, nextLocation.cell2)
 
-			// generate the next placement
-			placement := &DominoPlacement{
-				cell1Identifier: nextLocation.cell1,
-				cell1Value:      o.cell1Val,
-				cell2Identifier: nextLocation.cell2,
-				cell2Value:      o.cell2Val,
-				printString:     nextDomino.String(),
+			// and make sure the rest of the locations can still each be given one of the dominoes left
+			var nextMatching *dominoMatching
+			if ok {
+				if nextMatching, ok = matching.place(nextLocation, kindIdx, nextDomains); !ok {
+					debugPrint(fmt.Printf, "domino %s in location %s leaves another location without a domino...\n", nextDomino, nextLocation)
+				}
 			}
 
-			// remove the domino since it will have been placed
-			delete(unplacedDominoes, nextDomino.identifier)
-
-			// track the placement
-			placementsSoFar = append(placementsSoFar, *placement)
+			if ok {
+				// remove the domino since it will have been placed
+				unplacedDominoes[kindIdx]--
 
-			// perform the next placement recursively (concurrently, if still allowed)
-			placeDomino(game, remainingLocations, unplacedDominoes, placementsSoFar, outPossibleSolutions)
+				// perform the next placement recursively, or hand it off with its own copy of the state if another
+				// worker is idle
+				if worker.shouldSplit(len(placementsSoFar)) {
+					dominoesCopy, placementsCopy := slices.Clone(unplacedDominoes), slices.Clone(placementsSoFar)
+					valuesCopy, conditionsCopy := cellValues.clone(), conditions.clone()
+					worker.spawn(func(w *schedulerWorker) {
+						placeDomino(ctx, game, budget, w, table, order, remainingLocations, dominoesCopy, placementsCopy, valuesCopy, conditionsCopy, nextDomains, nextMatching, emit)
+					})
+					alive = true
+				} else if !placeDomino(ctx, game, budget, worker, table, order, remainingLocations, unplacedDominoes, placementsSoFar, cellValues, conditions, nextDomains, nextMatching, emit) {
+					alive = true
+				} else {
+					budget.countBacktrack()
+				}
 
-			// backtrack
-			placementsSoFar = placementsSoFar[0 : len(placementsSoFar)-1]
-			unplacedDominoes[nextDomino.identifier] = nextDomino
+				unplacedDominoes[kindIdx]++
+			} else {
+				budget.countBacktrack()
+			}
+		} else {
+			debugPrint(fmt.Printf, "domino %s breaks a condition in location %s...\n", nextDomino, nextLocation)
+			budget.countBacktrack()
 		}
+
+		// backtrack
+		placementsSoFar = placementsSoFar[0 : len(placementsSoFar)-1]
+		cellValues.unset(nextLocation.cell1.index)
+		cellValues.unset(nextLocation.cell2.index)
+		conditions.unfill(nextLocation.cell1, o[0])
+		conditions.unfill(nextLocation.cell2, o[1])
 	}
+
+	// a search cut short might have missed solutions, so only a full one proves a dead end
+	if alive || ctx.Err() != nil || budget.Exceeded() {
+		return false
+	}
+	table.store(hash, 0)
+	return true
 }
@@ -0,0 +1,166 @@
+package solver
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// Zobrist hashing of search states - every part a state can be made of gets a random 64 bit key, and a state's hash
+// is the XOR of the keys of its parts. Two different states only share a hash by (astronomically unlikely) accident.
+type zobristKeys struct {
+	filledCells []uint64    // by cell index, for cells that are filled
+	locations   [][2]uint64 // by the index of a location's first cell in reading order, then right or below
+	dominoes    [][]uint64  // by domino kind index, then the number of that kind left
+	residuals   [][]uint64  // by condition index, then the condition's residual (see conditionTracker.residual)
+}
+
+// the keys are seeded the same way every time, so hashes (and anything built on them) are repeatable
+const zobristSeed = 0x5049505321
+
+// creates keys for every part a search state of the game can be made of
+func newZobristKeys(g *Game) *zobristKeys {
+	r := rand.New(rand.NewPCG(zobristSeed, zobristSeed))
+	z := &zobristKeys{
+		filledCells: make([]uint64, len(g.cells)),
+		locations:   make([][2]uint64, len(g.cells)),
+		dominoes:    make([][]uint64, len(g.dominoKinds)),
+		residuals:   make([][]uint64, len(g.conditions)),
+	}
+	for i := range g.cells {
+		z.filledCells[i] = r.Uint64()
+		z.locations[i] = [2]uint64{r.Uint64(), r.Uint64()}
+	}
+	inventory := g.newDominoInventory()
+	for kindIdx, count := range inventory {
+		z.dominoes[kindIdx] = make([]uint64, count+1)
+		for i := range z.dominoes[kindIdx] {
+			z.dominoes[kindIdx][i] = r.Uint64()
+		}
+	}
+	for _, cond := range g.conditions {
+		// sums never go past every cell holding the biggest value, and value sets are a bitmask of the 7 values
+		z.residuals[cond.index] = make([]uint64, max(maxPipValue*len(cond.cells), int(fullValueDomain))+1)
+		for i := range z.residuals[cond.index] {
+			z.residuals[cond.index][i] = r.Uint64()
+		}
+	}
+	return z
+}
+
+// hash of the dominoes left and what is left of every condition - the part of a state every search shares
+func (z *zobristKeys) remainingHash(unplacedDominoes dominoInventory, conditions *conditionTracker) uint64 {
+	h := uint64(0)
+	for kindIdx, count := range unplacedDominoes {
+		h ^= z.dominoes[kindIdx][count]
+	}
+	for condIdx := range z.residuals {
+		h ^= z.residuals[condIdx][conditions.residual(condIdx)]
+	}
+	return h
+}
+
+// hash of a state of a search that fills cells one by one, like the unified engine - the filled cells, the dominoes
+// left, and what is left of every condition
+func (z *zobristKeys) cellsHash(filled cellSet, unplacedDominoes dominoInventory, conditions *conditionTracker) uint64 {
+	h := z.remainingHash(unplacedDominoes, conditions)
+	for i, key := range z.filledCells {
+		if filled.has(i) {
+			h ^= key
+		}
+	}
+	return h
+}
+
+// hash of a state of a search that fills the locations of an arrangement - the locations left to fill (which also
+// says which cells are filled), the dominoes left, and what is left of every condition. Locations are hashed on
+// their own rather than through the arrangement, so states from different arrangements with the same locations
+// left are the same state.
+func (z *zobristKeys) locationsHash(unfilledLocations []DominoArrangementLocation, unplacedDominoes dominoInventory, conditions *conditionTracker) uint64 {
+	h := z.remainingHash(unplacedDominoes, conditions)
+	for _, l := range unfilledLocations {
+		first, second := l.cell1, l.cell2
+		if first.index > second.index {
+			first, second = second, first
+		}
+		if first.neighborRight == second {
+			h ^= z.locations[first.index][0]
+		} else {
+			h ^= z.locations[first.index][1]
+		}
+	}
+	return h
+}
+
+// rough memory used by each entry in a transposition table, including the map's own overhead
+const transpositionEntryBytes = 40
+
+// TranspositionStats - how much use a search made of its transposition table
+type TranspositionStats struct {
+	Hits     int64 // states looked up that had already been searched
+	Misses   int64 // states looked up for the first time
+	Stored   int64 // states recorded
+	Rejected int64 // states that weren't recorded because the table was full
+}
+
+// transpositionTable - what is known about search states that have already been searched in full, by Zobrist hash -
+// the number of solutions below them, which for dead states is 0. Safe for concurrent use, so workers of the same
+// search can share one. A nil table remembers nothing.
+type transpositionTable struct {
+	mu         sync.Mutex
+	entries    map[uint64]int64
+	maxEntries int
+	stats      TranspositionStats
+}
+
+// creates a table holding as many states as fit in maxBytes, or nil for no table if maxBytes is 0
+func newTranspositionTable(maxBytes int64) *transpositionTable {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &transpositionTable{
+		entries:    make(map[uint64]int64),
+		maxEntries: int(maxBytes / transpositionEntryBytes),
+	}
+}
+
+// looks up the number of solutions below a state, returning false if the state hasn't been searched in full yet
+func (t *transpositionTable) lookup(hash uint64) (int64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count, ok := t.entries[hash]
+	if ok {
+		t.stats.Hits++
+	} else {
+		t.stats.Misses++
+	}
+	return count, ok
+}
+
+// records the number of solutions below a state that was searched in full - once the table is full, new states are
+// turned away and the ones already in it are kept
+func (t *transpositionTable) store(hash uint64, count int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.entries[hash]; !ok && len(t.entries) >= t.maxEntries {
+		t.stats.Rejected++
+		return
+	}
+	t.entries[hash] = count
+	t.stats.Stored++
+}
+
+// Stats - a snapshot of how the table has been used so far
+func (t *transpositionTable) Stats() TranspositionStats {
+	if t == nil {
+		return TranspositionStats{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
@@ -0,0 +1,141 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// GetSolutions - finds valid solutions in a single pass by tiling the board and assigning domino values together.
+// Unlike the arrangement-then-fill pipeline, a violated condition here prunes every tiling that shares the
+// partial placement, instead of being rediscovered once per arrangement. Stops early if the context is cancelled or
+// the budget runs out.
+func GetSolutions(ctx context.Context, game *Game, budget *SearchBudget, outSolutions chan<- Solution) {
+	if game == nil {
+		panic("nil game")
+	}
+	searchUnifiedFrom(ctx, game, budget, nil, func(s Solution) {
+		if sendOrCancel(ctx, outSolutions, s) {
+			debugPrint(fmt.Println, "All dominoes placed and solution added...")
+		}
+	})
+}
+
+// starts the unified search, handing each solution to emit - splits the search across the scheduler if given a worker
+func searchUnifiedFrom(ctx context.Context, game *Game, budget *SearchBudget, worker *schedulerWorker, emit func(Solution)) {
+	debugPrint(fmt.Println, strings.Repeat("*", 64))
+	defer debugPrint(fmt.Println, strings.Repeat("*", 64)+"\n")
+	debugPrint(fmt.Println, "Calculating solutions with unified search...")
+
+	// track unplaced dominoes and filled cells as time progresses
+	unplacedDominoes := game.newDominoInventory()
+	cellValues := newBoardValues(len(game.cells))
+	placementsSoFar := make([]DominoPlacement, 0)
+	conditions := newConditionTracker(game)
+
+	placeDominoUnified(ctx, game, budget, worker, cellValues, conditions, unplacedDominoes, placementsSoFar, emit)
+}
+
+// recursively picks the next unfilled cell, pairs it with a free neighbor, and places each remaining domino
+// there in both orientations, checking the conditions of the two cells before going any deeper
+//
+// cells are filled in reading order (top to bottom, left to right), so the next cell to fill is always the first
+// unfilled one by index - its neighbors to the left and above are already filled, so only right and below are tried
+func placeDominoUnified(
+	ctx context.Context,
+	game *Game,
+	budget *SearchBudget,
+	worker *schedulerWorker,
+	cellValues *boardValues,
+	conditions *conditionTracker,
+	unplacedDominoes dominoInventory,
+	placementsSoFar []DominoPlacement,
+	emit func(Solution),
+) {
+	if game == nil {
+		panic("nil game")
+	}
+	if emit == nil {
+		panic("nil solution handler")
+	}
+	if ctx.Err() != nil || !budget.spendNode() {
+		return
+	}
+
+	// base case - every cell is filled, and every condition was checked as its last cell was filled
+	nextCellIndex := game.inPlayCells.minus(cellValues.filled).first()
+	if nextCellIndex == -1 {
+		if unplacedDominoes.total() != 0 {
+			panic("mismatch between number of dominoes and places to put them")
+		}
+		// need to do this copy to prevent backtracking bugs
+		placementsCopy := make([]DominoPlacement, len(placementsSoFar))
+		copy(placementsCopy, placementsSoFar)
+		emit(Solution{dominoPlacements: placementsCopy})
+		return
+	}
+
+	nextCell := game.cells[nextCellIndex]
+	neighborFound := false
+	for _, neighbor := range []*cell{nextCell.neighborRight, nextCell.neighborBelow} {
+		if neighbor == nil {
+			continue
+		}
+		if cellValues.filled.has(neighbor.index) {
+			continue
+		}
+		neighborFound = true
+
+		// try every kind of domino that is left, in both orientations if they are different
+		for kindIdx, nextDomino := range game.dominoKinds {
+			if unplacedDominoes[kindIdx] == 0 {
+				continue
+			}
+			for _, o := range nextDomino.orientations() {
+				cellValues.set(nextCell.index, o[0])
+				cellValues.set(neighbor.index, o[1])
+				conditions.fill(nextCell, o[0])
+				conditions.fill(neighbor, o[1])
+
+				// only the conditions touching the two cells can change by placing a domino here
+				if conditions.holds(nextCell) && conditions.holds(neighbor) {
+					debugPrint(fmt.Printf, "placing domino %s in cells %s & %s...\n", nextDomino, nextCell, neighbor)
+
+					unplacedDominoes[kindIdx]--
+					placementsSoFar = append(placementsSoFar, DominoPlacement{
+						cell1:       nextCell,
+						cell1Value:  o[0],
+						cell2:       neighbor,
+						cell2Value:  o[1],
+						printString: nextDomino.String(),
+					})
+
+					// recurse, or hand it off with its own copy of the state if another worker is idle
+					if worker.shouldSplit(len(placementsSoFar)) {
+						valuesCopy, conditionsCopy := cellValues.clone(), conditions.clone()
+						dominoesCopy, placementsCopy := slices.Clone(unplacedDominoes), slices.Clone(placementsSoFar)
+						worker.spawn(func(w *schedulerWorker) {
+							placeDominoUnified(ctx, game, budget, w, valuesCopy, conditionsCopy, dominoesCopy, placementsCopy, emit)
+						})
+					} else {
+						placeDominoUnified(ctx, game, budget, worker, cellValues, conditions, unplacedDominoes, placementsSoFar, emit)
+					}
+
+					// backtrack
+					placementsSoFar = placementsSoFar[0 : len(placementsSoFar)-1]
+					unplacedDominoes[kindIdx]++
+				}
+
+				cellValues.unset(neighbor.index)
+				cellValues.unset(nextCell.index)
+				conditions.unfill(neighbor, o[1])
+				conditions.unfill(nextCell, o[0])
+			}
+		}
+	}
+
+	if !neighborFound {
+		debugPrint(fmt.Printf, "Cell %s was orphaned - abandoning this placement...\n", nextCell.identifier())
+	}
+}
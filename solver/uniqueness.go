@@ -0,0 +1,118 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// UniquenessVerdict - whether a puzzle has exactly one solution
+type UniquenessVerdict int
+
+const (
+	// UniquenessVerdictUnique - exactly one solution was found, and every possibility was searched
+	UniquenessVerdictUnique UniquenessVerdict = iota
+	// UniquenessVerdictAmbiguous - two different solutions were found
+	UniquenessVerdictAmbiguous
+	// UniquenessVerdictUnsolvable - no solutions were found, and every possibility was searched
+	UniquenessVerdictUnsolvable
+	// UniquenessVerdictUnknown - the search was cut short before a second solution was found or ruled out
+	UniquenessVerdictUnknown
+)
+
+func (v UniquenessVerdict) String() string {
+	switch v {
+	case UniquenessVerdictUnique:
+		return "unique"
+	case UniquenessVerdictAmbiguous:
+		return "ambiguous"
+	case UniquenessVerdictUnsolvable:
+		return "unsolvable"
+	case UniquenessVerdictUnknown:
+		return "unknown"
+	default:
+		panic("unhandled uniqueness verdict")
+	}
+}
+
+// UniquenessResult - the outcome of checking a puzzle for a unique solution
+type UniquenessResult struct {
+	Verdict UniquenessVerdict
+	// the solution if unique, both solutions if ambiguous, and whatever was found before the search stopped if unknown
+	Solutions []Solution
+	// cells that differ between the two solutions of an ambiguous puzzle, in reading order
+	DifferingCells []string
+	Search         SearchResult
+}
+
+// CheckUniqueness - searches for solutions until a second one turns up, to prove whether the puzzle has exactly one.
+// Solutions only count as different under opts.Identity, so swapping identical dominoes or flipping a double never
+// makes a puzzle ambiguous. Any solution limit in the options is replaced.
+func CheckUniqueness(ctx context.Context, game *Game, opts SearchOptions) UniquenessResult {
+	if game == nil {
+		panic("nil game")
+	}
+	opts.MaxSolutions = 2
+	search := Search(ctx, game, opts)
+
+	result := UniquenessResult{Solutions: search.Solutions, Search: search}
+	switch {
+	case len(search.Solutions) == 2:
+		result.Verdict = UniquenessVerdictAmbiguous
+		result.DifferingCells = search.Solutions[0].DifferingCells(search.Solutions[1], opts.Identity)
+	case !search.Exhaustive():
+		result.Verdict = UniquenessVerdictUnknown
+	case len(search.Solutions) == 1:
+		result.Verdict = UniquenessVerdictUnique
+	default:
+		result.Verdict = UniquenessVerdictUnsolvable
+	}
+	debugPrint(fmt.Printf, "Uniqueness check found the puzzle %s\n", result.Verdict.String())
+	return result
+}
+
+// DifferingCells - the identifiers of the cells where two solutions differ under the identity, in reading order. By
+// pip grid, that is every cell holding a different value. By domino layout, it also includes cells whose domino
+// covers a different neighbor.
+func (s Solution) DifferingCells(o Solution, identity SolutionIdentity) []string {
+	type coveredCell struct {
+		c       *cell
+		value   int
+		partner int // index of the other cell under the same domino
+	}
+	cover := func(s Solution) map[int]coveredCell {
+		covered := make(map[int]coveredCell, 2*len(s.dominoPlacements))
+		for _, p := range s.dominoPlacements {
+			covered[p.cell1.index] = coveredCell{c: p.cell1, value: p.cell1Value, partner: p.cell2.index}
+			covered[p.cell2.index] = coveredCell{c: p.cell2, value: p.cell2Value, partner: p.cell1.index}
+		}
+		return covered
+	}
+	sCovered, oCovered := cover(s), cover(o)
+
+	differing := make([]*cell, 0)
+	for index, sc := range sCovered {
+		oc := oCovered[index]
+		switch identity {
+		case SolutionIdentityPipGrid:
+			if sc.value != oc.value {
+				differing = append(differing, sc.c)
+			}
+		case SolutionIdentityDominoLayout:
+			if sc.value != oc.value || sc.partner != oc.partner {
+				differing = append(differing, sc.c)
+			}
+		default:
+			panic("unhandled solution identity")
+		}
+	}
+	slices.SortFunc(differing, func(l, r *cell) int {
+		return l.index - r.index
+	})
+
+	identifiers := make([]string, 0, len(differing))
+	for _, c := range differing {
+		identifiers = append(identifiers, c.identifier())
+	}
+	return identifiers
+}
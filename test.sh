@@ -2,27 +2,199 @@
 
 SCRIPT_DIR="$(dirname "$(realpath "$0")")"
 TEST_FILE_DIR="test_files"
+ENGINES=("pipeline" "unified" "dlx" "decompose")
 
 # determines from solve output if a puzzle was successfully solved
 SUCCESS_GREP="grep \"NYT Pips Solver Completed\" | grep -q \"Found\""
 
 test_passed="true"
 
-# test each file
+# test each file with each engine
 echo -e "Running known working test files...\n"
+for engine in "${ENGINES[@]}"; do
+    for file in "$SCRIPT_DIR/$TEST_FILE_DIR/"*.json; do
+        echo -e "Checking "$file" with engine "$engine"...\n"
+
+        run_output=$(go run . --f "$file" --e "$engine")
+        echo -e "${run_output}\n"
+
+        if echo "$run_output" | (eval $SUCCESS_GREP); then
+            echo -e "File success...\n"
+        else
+            echo -e "File failure...\n"
+            test_passed="false"
+        fi
+    done
+done
+
+# edge case boards have to be solved by every engine too
+echo -e "Running edge case files...\n"
+for engine in "${ENGINES[@]}"; do
+    for file in "$SCRIPT_DIR/$TEST_FILE_DIR/edge_cases/"*.json; do
+        run_output=$(go run . --f "$file" --e "$engine" 2>&1)
+        echo -e "$file with engine $engine - $(echo "$run_output" | grep "NYT Pips Solver Completed")\n"
+        if ! echo "$run_output" | (eval $SUCCESS_GREP); then
+            echo -e "$run_output\nFile failure...\n"
+            test_passed="false"
+        fi
+    done
+done
+
+# a search cut short by the node limit has to say so and exit with code 2 - built once, since go run hides exit codes
+echo -e "Checking searches cut short by the node limit...\n"
+solver_bin="$(mktemp)"
+go build -o "$solver_bin" .
+for args in "--e pipeline" "--e unified" "--e dlx" "--e decompose" "--count"; do
+    file="$SCRIPT_DIR/$TEST_FILE_DIR/2025_08_29_hard.json"
+    run_output=$("$solver_bin" --f "$file" $args --max-nodes 5)
+    exit_code=$?
+    report=$(echo "$run_output" | grep "Stopped early")
+    echo -e "$args with --max-nodes 5 - exit code $exit_code, $report\n"
+    if [[ "$exit_code" != "2" ]] || ! echo "$report" | grep -q "node limit after visiting 5 nodes"; then
+        echo -e "Node limit not reported...\n"
+        test_passed="false"
+    fi
+done
+
+# the other modes have to exit with code 2 as well, with their own way of saying they were cut short
+limited_file="$(mktemp --suffix .json)"
+declare -A LIMITED_MODES=(
+    ["--f $SCRIPT_DIR/$TEST_FILE_DIR/2025_08_29_hard.json --unique --max-nodes 5"]="Uniqueness unknown"
+    ["--f $SCRIPT_DIR/$TEST_FILE_DIR/2025_08_29_hard.json --rate --max-nodes 5"]="partial, the search hit the search node limit"
+    ["--f $SCRIPT_DIR/$TEST_FILE_DIR/2025_08_29_hard.json --explain --max-nodes 5"]="Stopped early - the techniques ran out"
+    ["--f $SCRIPT_DIR/$TEST_FILE_DIR/tutorial.json --hint --board $SCRIPT_DIR/$TEST_FILE_DIR/boards/tutorial_dead_end.json --max-nodes 1"]="Stopped early - checking that the board can still be finished"
+    ["--f $SCRIPT_DIR/$TEST_FILE_DIR/2025_08_29_hard.json --minimize $limited_file --max-nodes 5"]="ran out of time or nodes before the puzzle could be proven unique"
+    ["--generate $limited_file --max-nodes 5 --attempts 2"]="ran out of search budget checking uniqueness"
+)
+for args in "${!LIMITED_MODES[@]}"; do
+    run_output=$("$solver_bin" $args)
+    exit_code=$?
+    echo -e "$args - exit code $exit_code\n"
+    if [[ "$exit_code" != "2" ]] || ! echo "$run_output" | grep -q "${LIMITED_MODES[$args]}"; then
+        echo -e "$run_output\nNode limit not reported...\n"
+        test_passed="false"
+    fi
+done
+rm -f "$solver_bin" "$limited_file"
+
+# boards that can't be tiled have to be turned away with the reason why
+echo -e "Checking untileable boards are diagnosed...\n"
+declare -A UNTILEABLE_REASONS=(
+    ["isolated_cell"]="has no neighbors in play"
+    ["odd_component"]="which is odd"
+    ["checkerboard_imbalance"]="in a checkerboard coloring"
+    ["wrong_domino_count"]="need 3 dominoes, but there are 2"
+)
+for name in "${!UNTILEABLE_REASONS[@]}"; do
+    file="$SCRIPT_DIR/$TEST_FILE_DIR/untileable/$name.json"
+    run_output=$(go run . --f "$file" | grep "^Error")
+    echo -e "$file - $run_output\n"
+    if ! echo "$run_output" | grep -q "can't be tiled with dominoes - .*${UNTILEABLE_REASONS[$name]}"; then
+        echo -e "Untileable board not diagnosed...\n"
+        test_passed="false"
+    fi
+done
+
+# hints have to notice boards that are wrong or already finished, and otherwise give a hint with its reason
+echo -e "Checking hints from partial boards...\n"
+declare -A HINT_EXPECTATIONS=(
+    ["empty"]="^Hint: .* (dead-end pairing)"
+    ["tutorial_completed"]="board is already solved"
+    ["tutorial_contradictory"]="break \"Cells 2:0 & 2:1 must add up to 10\""
+    ["tutorial_dead_end"]="no solution can be reached from the dominoes placed so far"
+)
+for board in "${!HINT_EXPECTATIONS[@]}"; do
+    board_args=()
+    if [[ "$board" != "empty" ]]; then
+        board_args=(--board "$SCRIPT_DIR/$TEST_FILE_DIR/boards/$board.json")
+    fi
+    run_output=$(go run . --f "$SCRIPT_DIR/$TEST_FILE_DIR/tutorial.json" --hint "${board_args[@]}")
+    hint=$(echo "$run_output" | grep -E "^(Hint|Why|Something is wrong|Nothing left)")
+    echo -e "$board board - $hint\n"
+    if ! echo "$hint" | grep -q "${HINT_EXPECTATIONS[$board]}"; then
+        echo -e "Unexpected hint...\n"
+        test_passed="false"
+    fi
+    if [[ "$board" == "empty" ]] && ! echo "$hint" | grep -q "^Why: "; then
+        echo -e "Hint without a reason...\n"
+        test_passed="false"
+    fi
+done
+
+# counting has to agree exactly with the number of solutions the default engine finds
+echo -e "Checking solution counts against the enumerated solutions...\n"
 for file in "$SCRIPT_DIR/$TEST_FILE_DIR/"*.json; do
-    echo -e "Checking "$file"...\n"
+    found=$(go run . --f "$file" | grep "NYT Pips Solver Completed" | grep -oE "Found (a|[0-9]+) valid" | grep -oE "(a|[0-9]+) " | sed 's/^a $/1 /' | tr -d ' ')
+    counted=$(go run . --f "$file" --count | grep "NYT Pips Solver Completed" | grep -oE "Counted [0-9]+" | grep -oE "[0-9]+")
+    echo -e "$file - found ${found:-0}, counted ${counted:-none}\n"
+    if [[ "${found:-0}" != "$counted" ]]; then
+        echo -e "Count mismatch...\n"
+        test_passed="false"
+    fi
+done
 
-    run_output=$(go run . --f "$file")
-    echo -e "${run_output}\n"
+# dated NYT files are labeled with their difficulty, which the rating should agree with
+echo -e "Checking difficulty ratings against the NYT labels...\n"
+for file in "$SCRIPT_DIR/$TEST_FILE_DIR/"*_{easy,medium,hard}.json; do
+    [[ -f "$file" ]] || continue
+    label="${file##*_}"
+    label="${label%.json}"
+    rating=$(go run . --f "$file" --rate | grep "^Difficulty")
+    echo -e "$file - labeled $label, rated $rating\n"
+    if ! echo "$rating" | grep -q "($label)"; then
+        echo -e "Rating mismatch...\n"
+        test_passed="false"
+    fi
+done
+
+# the handmade easy puzzle's rating was worked out by hand - the only fitting domino (20), 8 deduction steps (1.6),
+# 1 tiling (2), 4 search nodes (2.3), and no backtracks or branching
+rating=$(go run . --f "$SCRIPT_DIR/$TEST_FILE_DIR/handmade_easy.json" --rate | grep "^Difficulty")
+if [[ "$rating" != "Difficulty 25.9 (easy)" ]]; then
+    echo -e "handmade_easy.json rated $rating instead of 25.9 (easy)...\n"
+    test_passed="false"
+fi
 
-    if echo "$run_output" | (eval $SUCCESS_GREP); then
-        echo -e "File success...\n"
-    else
-        echo -e "File failure...\n"
+# generated puzzles have to come out unique with every engine
+echo -e "Checking generated puzzles are unique...\n"
+generated_file="$(mktemp --suffix .json)"
+for seed in 1 2 3; do
+    go run . --generate "$generated_file" --seed "$seed" > /dev/null
+    for engine in "${ENGINES[@]}"; do
+        verdict=$(go run . --f "$generated_file" --e "$engine" --unique | grep "NYT Pips Solver Completed")
+        echo -e "Seed $seed with engine $engine - $verdict\n"
+        if ! echo "$verdict" | grep -q "is unique"; then
+            echo -e "Generated puzzle not unique...\n"
+            test_passed="false"
+        fi
+    done
+done
+# and the same seed has to generate the same puzzle every time
+go run . --generate "$generated_file" --seed 1 > /dev/null
+first_generated=$(cat "$generated_file")
+go run . --generate "$generated_file" --seed 1 > /dev/null
+if [[ "$first_generated" != "$(cat "$generated_file")" ]]; then
+    echo -e "Seed 1 generated different puzzles...\n"
+    test_passed="false"
+fi
+rm -f "$generated_file"
+
+# minimized puzzles have to stay unique - only puzzles that are unique to begin with can be minimized
+echo -e "Checking minimized puzzles are still unique...\n"
+minimized_file="$(mktemp --suffix .json)"
+for file in "$SCRIPT_DIR/$TEST_FILE_DIR/"*.json; do
+    original_verdict=$(go run . --f "$file" --unique | grep "NYT Pips Solver Completed")
+    echo "$original_verdict" | grep -q "is unique" || continue
+    rm -f "$minimized_file"
+    go run . --f "$file" --minimize "$minimized_file" > /dev/null
+    verdict=$(go run . --f "$minimized_file" --unique | grep "NYT Pips Solver Completed")
+    echo -e "$file minimized - $verdict\n"
+    if ! echo "$verdict" | grep -q "is unique"; then
+        echo -e "Minimized puzzle not unique...\n"
         test_passed="false"
     fi
 done
+rm -f "$minimized_file"
 
 # return the overall success/failure status
 if [[ "$test_passed" == "false" ]]; then